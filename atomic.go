@@ -0,0 +1,63 @@
+package bitarray
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+var bigEndian = func() bool {
+	x := uint16(1)
+	return *(*uint8)(unsafe.Pointer(&x)) == 0
+}()
+
+// SetAtomic atomically sets the bit at index idx to 1.
+//
+// The atomic methods SetAtomic, UnsetAtomic, and GetAtomic may be called concurrently
+// with each other, but not concurrently with any other method.
+func (ba *BitArray) SetAtomic(idx int) {
+	ba.checkIdx(idx)
+	w, mask := ba.atomicWord(idx)
+	for {
+		old := atomic.LoadUint32(w)
+		if old&mask != 0 || atomic.CompareAndSwapUint32(w, old, old|mask) {
+			return
+		}
+	}
+}
+
+// UnsetAtomic atomically sets the bit at index idx to 0.
+// See [BitArray.SetAtomic] for the concurrency rules.
+func (ba *BitArray) UnsetAtomic(idx int) {
+	ba.checkIdx(idx)
+	w, mask := ba.atomicWord(idx)
+	for {
+		old := atomic.LoadUint32(w)
+		if old&mask == 0 || atomic.CompareAndSwapUint32(w, old, old&^mask) {
+			return
+		}
+	}
+}
+
+// GetAtomic atomically reports whether the bit at index idx is set.
+// See [BitArray.SetAtomic] for the concurrency rules.
+func (ba *BitArray) GetAtomic(idx int) bool {
+	ba.checkIdx(idx)
+	w, mask := ba.atomicWord(idx)
+	return atomic.LoadUint32(w)&mask != 0
+}
+
+// atomicWord returns the 32-bit word that contains the bit at index idx and the mask
+// for the bit within the word.
+func (ba *BitArray) atomicWord(idx int) (*uint32, uint32) {
+	n, i := idx/bitsN, idx%bitsN
+	start := n &^ 3
+	p := unsafe.Pointer(unsafe.SliceData(ba.data[start:]))
+	if uintptr(p)%4 != 0 || cap(ba.data)-start < 4 {
+		panic("storage not suitable for atomic access")
+	}
+	shift := (n - start) * bitsN
+	if bigEndian {
+		shift = (3 - (n - start)) * bitsN
+	}
+	return (*uint32)(p), 1 << (shift + i)
+}
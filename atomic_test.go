@@ -0,0 +1,114 @@
+package bitarray
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAtomic(t *testing.T) {
+	ba := New(10)
+	ba.SetAtomic(0)
+	ba.SetAtomic(9)
+	ba.SetAtomic(9)
+	ba.SetAtomic(5)
+	ba.UnsetAtomic(5)
+	ba.UnsetAtomic(4)
+	if got, want := ba.String(), "1000000001"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for i := 0; i < ba.Size(); i++ {
+		if got, want := ba.GetAtomic(i), ba.Get(i); got != want {
+			t.Errorf("%d: got %t, want %t", i, got, want)
+		}
+	}
+}
+
+func TestAtomicIdx(t *testing.T) {
+	funcs := []func(ba *BitArray){
+		func(ba *BitArray) { ba.SetAtomic(4) },
+		func(ba *BitArray) { ba.UnsetAtomic(-1) },
+		func(ba *BitArray) { ba.GetAtomic(4) },
+	}
+	for i, f := range funcs {
+		func() {
+			defer func() { recover() }()
+			f(New(4))
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestAtomicConcurrent(t *testing.T) {
+	tests := []func(size int) *BitArray{
+		func(size int) *BitArray { return New(size) },
+		func(size int) *BitArray { ba := New(1); ba.Grow(size - 1); return ba },
+		func(size int) *BitArray { ba := New(size); ba.Truncate(1); ba.Grow(size - 1); return ba },
+		func(size int) *BitArray { ba := New(size); ba.Resize(size / 3); ba.Resize(size); return ba },
+		func(size int) *BitArray { return FromBytes(make([]byte, size/8)) },
+		func(size int) *BitArray { return must(FromBytesLE(size, make([]byte, size/8))) },
+		func(size int) *BitArray { return must(FromBytesMSB(size, make([]byte, size/8))) },
+		func(size int) *BitArray { return must(ReadFrom(bytes.NewReader(make([]byte, size/8)), size)) },
+		func(size int) *BitArray { return Clone(New(size)) },
+		func(size int) *BitArray { return MustParse(strings.Repeat("0", size)) },
+		func(size int) *BitArray { return must(ParseHex(size, "0")) },
+		func(size int) *BitArray { return Slice(New(size+5), 5, size+5) },
+		func(size int) *BitArray { return ConcatAll(New(3), New(size-3)) },
+		func(size int) *BitArray {
+			b, _ := New(size).MarshalBinary()
+			ba := new(BitArray)
+			return must(ba, ba.UnmarshalBinary(b))
+		},
+		func(size int) *BitArray {
+			var buf bytes.Buffer
+			enc := gob.NewEncoder(&buf)
+			enc.Encode(size)
+			enc.Encode(make([]uint8, size/8))
+			ba := new(BitArray)
+			return must(ba, ba.UnmarshalBinary(buf.Bytes()))
+		},
+		func(size int) *BitArray {
+			ba := new(BitArray)
+			return must(ba, ba.UnmarshalText([]byte(strings.Repeat("0", size))))
+		},
+		func(size int) *BitArray { return must(UnmarshalCompact(New(size).appendDense([]byte{compactDense}))) },
+		func(size int) *BitArray { return must(UnmarshalCompact(New(size).appendRLE([]byte{compactRLE}))) },
+		func(size int) *BitArray { return must(UnmarshalCompact(New(size).appendSparse([]byte{compactSparse}))) },
+		func(size int) *BitArray {
+			var b Builder
+			for range size {
+				b.WriteBit(false)
+			}
+			return b.BitArray()
+		},
+	}
+	const n = 200
+	for i, f := range tests {
+		ba := f(n)
+		var wg sync.WaitGroup
+		for idx := 0; idx < n; idx++ {
+			wg.Add(1)
+			go func(idx int) {
+				defer wg.Done()
+				ba.SetAtomic(idx)
+				ba.GetAtomic((idx + 1) % n)
+				if idx%2 == 1 {
+					ba.UnsetAtomic(idx)
+				}
+			}(idx)
+		}
+		wg.Wait()
+		if got := ba.Count(); got != n/2 {
+			t.Errorf("%d: got %d, want %d", i, got, n/2)
+		}
+	}
+}
+
+func must(ba *BitArray, err error) *BitArray {
+	if err != nil {
+		panic(err)
+	}
+	return ba
+}
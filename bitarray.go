@@ -2,17 +2,27 @@
 //
 // The least significant bit is at index 0. In the string representation used by
 // [Parse], [MustParse], and [BitArray.String] it is the rightmost digit.
-// The byte slice used by [FromBytes] and [BitArray.ToBytes] is in big-endian order.
+// The byte slice used by [FromBytes] and [BitArray.ToBytes] is in big-endian order,
+// the one used by [FromBytesLE] and [BitArray.Bytes] is in little-endian order.
 package bitarray
 
 import (
 	"bytes"
+	"cmp"
+	"encoding/binary"
 	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
 	"math/bits"
+	"math/rand"
 	"slices"
 	"strings"
+	"unicode/utf8"
+	"unsafe"
 )
 
 const bitsN = 8
@@ -20,6 +30,9 @@ const bitsN = 8
 // BitArray type.
 type BitArray struct {
 	size int
+	// data must always be allocated with makeData (directly or via New, Clone, Grow,
+	// and so on), never with make or taken from the caller, because the atomic methods
+	// access it as 32-bit words.
 	data []uint8
 }
 
@@ -29,17 +42,29 @@ func New(size int, idx ...int) *BitArray {
 	if size <= 0 {
 		panic("size must be > 0")
 	}
-	n, r := size/bitsN, size%bitsN
-	if r > 0 {
-		n++
-	}
-	ba := BitArray{size, make([]uint8, n)}
+	ba := BitArray{size, makeData(dataLen(size))}
 	for _, i := range idx {
 		ba.Set(i)
 	}
 	return &ba
 }
 
+// makeData returns a byte slice with length n whose underlying array is allocated
+// as 32-bit words. This is required by the atomic methods.
+func makeData(n int) []uint8 {
+	w := make([]uint32, (n+3)/4)
+	return unsafe.Slice((*uint8)(unsafe.Pointer(unsafe.SliceData(w))), len(w)*4)[:n]
+}
+
+// dataLen returns the number of bytes needed for size bits.
+func dataLen(size int) int {
+	n, r := size/bitsN, size%bitsN
+	if r > 0 {
+		n++
+	}
+	return n
+}
+
 // Parse creates a new BitArray by parsing the given string. Space characters are ignored.
 // Returns an error if one of the characters in the string is not space, 0, or 1.
 func Parse(s string) (*BitArray, error) {
@@ -56,6 +81,95 @@ func Parse(s string) (*BitArray, error) {
 	return ba, nil
 }
 
+// ParseMSB creates a new BitArray by parsing the given string where, unlike [Parse],
+// the leftmost character is the bit at index 0 (MSB-first order). Space characters are
+// ignored. Returns an error if one of the characters in the string is not space, 0, or 1.
+func ParseMSB(s string) (*BitArray, error) {
+	rs := []rune(strings.ReplaceAll(s, " ", ""))
+	ba := New(len(rs))
+	for i, c := range rs {
+		if c == '1' {
+			ba.set(i)
+		} else if c != '0' {
+			return nil, fmt.Errorf("unknown character: %c", c)
+		}
+	}
+	return ba, nil
+}
+
+// ParseHex creates a new BitArray with size bits by parsing the given string of
+// hexadecimal digits. The last digit holds the bits at indexes 0-3 and so on, which
+// is the format returned by [BitArray.ToHex]. Space characters are ignored.
+// Returns an error if the string is empty, one of the characters is not space or a
+// hexadecimal digit, or a bit at an index >= size would be set. Panics if size <= 0.
+func ParseHex(size int, s string) (*BitArray, error) {
+	return parseRadix(size, s, 4)
+}
+
+// ParseAny creates a new BitArray by parsing the given string as binary, hexadecimal, or
+// octal digits if it has the prefix "0b", "0x", or "0o" (or the upper-case variants)
+// respectively, and as binary digits if it has no prefix. The size is the number of
+// digits times the number of bits per digit, e.g. 8 for "0xFF". Space characters
+// are ignored. Returns an error if there are no digits or a digit is invalid.
+func ParseAny(s string) (*BitArray, error) {
+	s = strings.ReplaceAll(s, " ", "")
+	k := 1
+	if len(s) >= 2 && s[0] == '0' {
+		switch s[1] {
+		case 'b', 'B':
+			s = s[2:]
+		case 'x', 'X':
+			k, s = 4, s[2:]
+		case 'o', 'O':
+			k, s = 3, s[2:]
+		}
+	}
+	n := utf8.RuneCountInString(s)
+	if n == 0 {
+		return nil, errors.New("empty string")
+	}
+	return parseRadix(n*k, s, k)
+}
+
+// parseRadix parses a string of digits with k bits each.
+func parseRadix(size int, s string, k int) (*BitArray, error) {
+	ba := New(size)
+	rs := []rune(strings.ReplaceAll(s, " ", ""))
+	if len(rs) == 0 {
+		return nil, errors.New("empty string")
+	}
+	for i := range rs {
+		c := rs[len(rs)-1-i]
+		d := digitValue(c)
+		if d < 0 || d >= 1<<k {
+			return nil, fmt.Errorf("unknown character: %c", c)
+		}
+		for j := 0; j < k; j++ {
+			if d&(1<<j) == 0 {
+				continue
+			}
+			if idx := i*k + j; idx < size {
+				ba.set(idx)
+			} else {
+				return nil, fmt.Errorf("value does not fit in %d bits", size)
+			}
+		}
+	}
+	return ba, nil
+}
+
+func digitValue(c rune) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
 // MustParse creates a new BitArray by parsing the given string. Space characters are
 // ignored. Panics if one of the characters in the string is not space, 0, or 1.
 func MustParse(s string) *BitArray {
@@ -66,9 +180,48 @@ func MustParse(s string) *BitArray {
 	return ba
 }
 
+// RangeMask creates a new BitArray with size bits and the bits at indexes [lo, hi]
+// (inclusive) set to 1. Panics if size <= 0 or not 0 <= lo <= hi < size.
+func RangeMask(size, lo, hi int) *BitArray {
+	ba := New(size)
+	if lo < 0 || lo > hi || hi >= size {
+		panic("range out of bounds")
+	}
+	for i := lo; i <= hi; i++ {
+		ba.set(i)
+	}
+	return ba
+}
+
+// LowMask creates a new BitArray with size bits and the lowest k bits (indexes [0, k))
+// set to 1. Panics if size <= 0 or k < 0 or k > size.
+func LowMask(size, k int) *BitArray {
+	ba := New(size)
+	if k < 0 || k > size {
+		panic("k out of range")
+	}
+	for i := 0; i < k; i++ {
+		ba.set(i)
+	}
+	return ba
+}
+
+// HighMask creates a new BitArray with size bits and the highest k bits (indexes
+// [size-k, size)) set to 1. Panics if size <= 0 or k < 0 or k > size.
+func HighMask(size, k int) *BitArray {
+	ba := New(size)
+	if k < 0 || k > size {
+		panic("k out of range")
+	}
+	for i := size - k; i < size; i++ {
+		ba.set(i)
+	}
+	return ba
+}
+
 // Clone clones the BitArray.
 func Clone(ba *BitArray) *BitArray {
-	sl := make([]uint8, len(ba.data))
+	sl := makeData(len(ba.data))
 	copy(sl, ba.data)
 	return &BitArray{ba.size, sl}
 }
@@ -92,6 +245,44 @@ func (ba *BitArray) SetAll() {
 	}
 }
 
+// Fill sets all bits to 1 if v is true, otherwise to 0.
+func (ba *BitArray) Fill(v bool) {
+	if v {
+		ba.SetAll()
+	} else {
+		ba.Clear()
+	}
+}
+
+// RandomFill sets each bit to 1 with probability p and to 0 otherwise, using r as the
+// source of randomness. If r is nil, the default source of package math/rand is used.
+// Panics if p is not in [0, 1].
+func (ba *BitArray) RandomFill(r *rand.Rand, p float64) {
+	if !(p >= 0 && p <= 1) {
+		panic("p out of range")
+	}
+	randUint64, randFloat64 := rand.Uint64, rand.Float64
+	if r != nil {
+		randUint64, randFloat64 = r.Uint64, r.Float64
+	}
+	if p == 0.5 {
+		for i := 0; i < len(ba.data); i += 8 {
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], randUint64())
+			copy(ba.data[i:], b[:])
+		}
+		ba.Normalize()
+		return
+	}
+	for i := 0; i < ba.size; i++ {
+		if randFloat64() < p {
+			ba.set(i)
+		} else {
+			ba.unset(i)
+		}
+	}
+}
+
 // Set sets the bit at index idx to 1.
 func (ba *BitArray) Set(idx int) {
 	ba.checkIdx(idx)
@@ -114,6 +305,11 @@ func (ba *BitArray) unset(idx int) {
 	ba.data[n] &^= 1 << i
 }
 
+func (ba *BitArray) flip(idx int) {
+	n, i := idx/bitsN, idx%bitsN
+	ba.data[n] ^= 1 << i
+}
+
 // Toggle toggles the state of the bit at index idx and reports whether it is set after being toggled.
 func (ba *BitArray) Toggle(idx int) bool {
 	ba.checkIdx(idx)
@@ -214,6 +410,59 @@ func (ba *BitArray) AndNot(other *BitArray) {
 	}
 }
 
+// Nand sets ba = ^(ba & other) (bitwise NAND).
+func (ba *BitArray) Nand(other *BitArray) {
+	ba.checkSize(other)
+	for i := range ba.data {
+		ba.data[i] = ^(ba.data[i] & other.data[i])
+	}
+	ba.Normalize()
+}
+
+// Nor sets ba = ^(ba | other) (bitwise NOR).
+func (ba *BitArray) Nor(other *BitArray) {
+	ba.checkSize(other)
+	for i := range ba.data {
+		ba.data[i] = ^(ba.data[i] | other.data[i])
+	}
+	ba.Normalize()
+}
+
+// Xnor sets ba = ^(ba ^ other) (bitwise XNOR).
+func (ba *BitArray) Xnor(other *BitArray) {
+	ba.checkSize(other)
+	for i := range ba.data {
+		ba.data[i] = ^(ba.data[i] ^ other.data[i])
+	}
+	ba.Normalize()
+}
+
+// Apply calls f for each bit in ascending order of the indexes with the index and the
+// value of the bit and sets the bit to the returned value. The bit is set before f is
+// called for the next index, so f sees the new values of the bits at lower indexes if
+// it reads them from ba.
+func (ba *BitArray) Apply(f func(idx int, v bool) bool) {
+	for i := 0; i < ba.size; i++ {
+		if f(i, ba.get(i)) {
+			ba.set(i)
+		} else {
+			ba.unset(i)
+		}
+	}
+}
+
+// Map returns a new BitArray with the same size where each bit is the value returned
+// by f for the index and the value of the bit of ba. ba is not changed.
+func (ba *BitArray) Map(f func(idx int, v bool) bool) *BitArray {
+	result := New(ba.size)
+	for i := 0; i < ba.size; i++ {
+		if f(i, ba.get(i)) {
+			result.set(i)
+		}
+	}
+	return result
+}
+
 // Not sets ba = ^ba.
 func (ba *BitArray) Not() {
 	for i := 0; i < len(ba.data)-1; i++ {
@@ -252,18 +501,180 @@ func (ba *BitArray) Rotate(n int) {
 	}
 }
 
+// RotateRange rotates the bits at indexes [start, end) by |n| bits. If n > 0 to the left,
+// if n < 0 to the right. Bits outside of the range are not changed.
+// Panics if not 0 <= start <= end <= ba.Size().
+func (ba *BitArray) RotateRange(start, end, n int) {
+	ba.checkRange(start, end)
+	if start == end {
+		return
+	}
+	w := Slice(ba, start, end)
+	w.Rotate(n)
+	ba.CopyFrom(start, w, 0, w.size)
+}
+
 // Shift shifts the bit array by |n| bits. If n > 0 to the left, if n < 0 to the right.
 func (ba *BitArray) Shift(n int) {
 	if n > 0 && n >= ba.size || n < 0 && -n >= ba.size {
 		ba.Clear()
 		return
 	}
+	if n%bitsN == 0 {
+		ba.shiftBytes(n / bitsN)
+	} else {
+		ba.shiftBits(n)
+	}
+}
+
+// ArithmeticShiftRight shifts the bit array by n bits to the right like Shift(-n), but
+// fills the vacated bits with the bit at index ba.Size()-1 (the sign bit of a signed
+// integer in two's complement) instead of 0. Does nothing if n <= 0.
+func (ba *BitArray) ArithmeticShiftRight(n int) {
+	if n <= 0 {
+		return
+	}
+	sign := ba.get(ba.size - 1)
+	ba.Shift(-n)
+	if sign {
+		for i := max(ba.size-n, 0); i < ba.size; i++ {
+			ba.set(i)
+		}
+	}
+}
+
+// shiftBytes shifts by k whole bytes with 0 < |k| < len(ba.data).
+func (ba *BitArray) shiftBytes(k int) {
+	if k > 0 {
+		copy(ba.data[k:], ba.data)
+		clear(ba.data[:k])
+		ba.Normalize()
+	} else if k < 0 {
+		k = -k
+		copy(ba.data, ba.data[k:])
+		clear(ba.data[len(ba.data)-k:])
+	}
+}
+
+func (ba *BitArray) shiftBits(n int) {
 	start, end := ba.moveBits(n)
 	for i := start; i < end; i++ {
 		ba.unset(i)
 	}
 }
 
+// Differentiate returns a new BitArray where the bit at index 0 equals the bit of ba
+// at index 0 and the bit at index i > 0 equals the XOR of the bits of ba at index i
+// and i-1. This is the inverse of [BitArray.Integrate].
+func (ba *BitArray) Differentiate() *BitArray {
+	result := New(ba.size)
+	prev := false
+	for i := 0; i < ba.size; i++ {
+		b := ba.get(i)
+		if b != prev {
+			result.set(i)
+		}
+		prev = b
+	}
+	return result
+}
+
+// Integrate returns a new BitArray where the bit at index i equals the XOR of the bits
+// of ba at indexes 0 to i (cumulative XOR). This is the inverse of
+// [BitArray.Differentiate].
+func (ba *BitArray) Integrate() *BitArray {
+	result := New(ba.size)
+	acc := false
+	for i := 0; i < ba.size; i++ {
+		if ba.get(i) {
+			acc = !acc
+		}
+		if acc {
+			result.set(i)
+		}
+	}
+	return result
+}
+
+// ToGray returns a new BitArray with the reflected binary Gray code of ba as an
+// unsigned integer, i.e. ba ^ (ba >> 1). This is the inverse of [BitArray.FromGray].
+func (ba *BitArray) ToGray() *BitArray {
+	result := Clone(ba)
+	result.Shift(-1)
+	result.Xor(ba)
+	return result
+}
+
+// FromGray returns a new BitArray with the unsigned integer whose reflected binary
+// Gray code is ba. This is the inverse of [BitArray.ToGray].
+func (ba *BitArray) FromGray() *BitArray {
+	result := New(ba.size)
+	acc := false
+	for i := ba.size - 1; i >= 0; i-- {
+		if ba.get(i) {
+			acc = !acc
+		}
+		if acc {
+			result.set(i)
+		}
+	}
+	return result
+}
+
+// Dilate returns a new BitArray where a bit is set if any bit of ba within the given
+// radius is set. Panics if radius < 0.
+func (ba *BitArray) Dilate(radius int) *BitArray {
+	if radius < 0 {
+		panic("radius must be >= 0")
+	}
+	result := New(ba.size)
+	ba.windowCounts(radius, func(i, cnt int) {
+		if cnt > 0 {
+			result.set(i)
+		}
+	})
+	return result
+}
+
+// Erode returns a new BitArray where a bit is set if all bits of ba within the given
+// radius are set. Bits outside of the bit array count as unset. Panics if radius < 0.
+func (ba *BitArray) Erode(radius int) *BitArray {
+	if radius < 0 {
+		panic("radius must be >= 0")
+	}
+	result := New(ba.size)
+	if radius >= ba.size {
+		return result
+	}
+	ba.windowCounts(radius, func(i, cnt int) {
+		if cnt == 2*radius+1 {
+			result.set(i)
+		}
+	})
+	return result
+}
+
+// windowCounts calls f for each index i with the number of set bits at the indexes
+// [i-radius, i+radius] that exist in the bit array.
+func (ba *BitArray) windowCounts(radius int, f func(i, cnt int)) {
+	radius = min(radius, ba.size)
+	cnt := 0
+	for i := 0; i < radius; i++ {
+		if ba.get(i) {
+			cnt++
+		}
+	}
+	for i := 0; i < ba.size; i++ {
+		if j := i + radius; j < ba.size && ba.get(j) {
+			cnt++
+		}
+		if j := i - radius - 1; j >= 0 && ba.get(j) {
+			cnt--
+		}
+		f(i, cnt)
+	}
+}
+
 func (ba *BitArray) moveBits(n int) (int, int) {
 	if n == 0 {
 		return 0, 0
@@ -293,129 +704,1039 @@ func (ba *BitArray) moveBits(n int) (int, int) {
 	return start, end
 }
 
-// Equal reports whether the two bit arrays are equal.
-func (ba *BitArray) Equal(other *BitArray) bool {
-	if ba.size != other.size {
-		return false
+// CopyFrom copies the bits of src at indexes [srcStart, srcEnd) to ba starting at
+// index dstStart. src may be ba, even if the ranges overlap. Panics if not
+// 0 <= srcStart <= srcEnd <= src.Size() or the destination range exceeds ba.
+func (ba *BitArray) CopyFrom(dstStart int, src *BitArray, srcStart, srcEnd int) {
+	src.checkRange(srcStart, srcEnd)
+	n := srcEnd - srcStart
+	ba.checkRange(dstStart, dstStart+n)
+	if src == ba {
+		src = Clone(ba)
 	}
-	for i := range ba.data {
-		if ba.data[i] != other.data[i] {
-			return false
+	for i := 0; i < n; i++ {
+		if src.get(srcStart + i) {
+			ba.set(dstStart + i)
+		} else {
+			ba.unset(dstStart + i)
 		}
 	}
-	return true
 }
 
-// Count returns the number of set bits.
-func (ba *BitArray) Count() int {
-	cnt := 0
-	for _, x := range ba.data {
-		cnt += bits.OnesCount8(x)
+// Grow increases the size of the bit array by n bits. The new bits are set to 0.
+// Panics if n < 0.
+func (ba *BitArray) Grow(n int) {
+	if n < 0 {
+		panic("n must be >= 0")
+	}
+	ba.size += n
+	m := dataLen(ba.size)
+	if m <= len(ba.data) {
+		return
+	}
+	if m <= cap(ba.data) {
+		n := len(ba.data)
+		ba.data = ba.data[:m]
+		clear(ba.data[n:])
+	} else {
+		data := makeData(max(m, 2*len(ba.data)))[:m]
+		copy(data, ba.data)
+		ba.data = data
 	}
-	return cnt
 }
 
-// LeadingZeros returns the number of leading unset bits.
-func (ba *BitArray) LeadingZeros() int {
-	cnt := 0
-	for i := len(ba.data) - 1; i >= 0; i-- {
-		if ba.data[i] == 0 {
-			cnt += bitsN
-		} else {
-			cnt += bits.LeadingZeros8(ba.data[i])
-			break
+// Append appends the given bits at the high end of the bit array, i.e. bits[0] gets
+// the index ba.Size() and so on.
+func (ba *BitArray) Append(bits ...bool) {
+	start := ba.size
+	ba.Grow(len(bits))
+	for i, b := range bits {
+		if b {
+			ba.set(start + i)
 		}
 	}
-	if x := ba.size % bitsN; x != 0 {
-		cnt -= bitsN - x
-	}
-	return cnt
 }
 
-// TrailingZeros returns the number of trailing unset bits.
-func (ba *BitArray) TrailingZeros() int {
-	cnt := 0
-	for _, x := range ba.data {
-		if x == 0 {
-			cnt += bitsN
+// InsertBit inserts a bit with value v at index idx. The bits at indexes >= idx are
+// moved up by one and the size increases by one. Panics if idx < 0 or idx > ba.Size().
+func (ba *BitArray) InsertBit(idx int, v bool) {
+	if idx != ba.size {
+		ba.checkIdx(idx)
+	}
+	ba.Grow(1)
+	for i := ba.size - 1; i > idx; i-- {
+		if ba.get(i - 1) {
+			ba.set(i)
 		} else {
-			cnt += bits.TrailingZeros8(x)
-			break
+			ba.unset(i)
 		}
 	}
-	if cnt > ba.size {
-		cnt = ba.size
+	if v {
+		ba.set(idx)
+	} else {
+		ba.unset(idx)
 	}
-	return cnt
 }
 
-// Size returns the size of the bit array.
-func (ba *BitArray) Size() int {
-	return ba.size
-}
-
-// String returns a string representation of the bit array.
-func (ba *BitArray) String() string {
-	baLen := len(ba.data)
-	n := ba.size % bitsN
-	if n == 0 {
-		n = bitsN
+// DeleteBit removes the bit at index idx. The bits at indexes > idx are moved down
+// by one and the size decreases by one. Panics if idx is out of range or ba.Size() == 1.
+func (ba *BitArray) DeleteBit(idx int) {
+	ba.checkIdx(idx)
+	if ba.size == 1 {
+		panic("size must be > 0")
 	}
-	s := fmt.Sprintf("%0*b", n, ba.data[baLen-1])
-	if baLen > 1 {
-		for i := baLen - 2; i >= 0; i-- {
-			s += fmt.Sprintf("%0*b", bitsN, ba.data[i])
+	for i := idx; i < ba.size-1; i++ {
+		if ba.get(i + 1) {
+			ba.set(i)
+		} else {
+			ba.unset(i)
 		}
 	}
-	return s
+	ba.Truncate(ba.size - 1)
 }
 
-func (ba *BitArray) checkIdx(idx int) {
-	if idx < 0 || idx >= ba.size {
-		panic("index out of range")
+// Truncate reduces the size of the bit array to newSize bits. The bits at indexes
+// >= newSize are discarded. Panics if newSize <= 0 or newSize > ba.Size().
+func (ba *BitArray) Truncate(newSize int) {
+	if newSize <= 0 || newSize > ba.size {
+		panic("new size out of range")
 	}
+	ba.size = newSize
+	ba.data = ba.data[:dataLen(newSize)]
+	ba.Normalize()
 }
 
-func (ba *BitArray) checkSize(other *BitArray) {
-	if ba.size != other.size {
-		panic("bit array sizes must be equal")
+// Resize changes the size of the bit array to newSize bits. If the size increases,
+// the new bits are set to 0 (see [BitArray.Grow]), if it decreases, the bits at indexes
+// >= newSize are discarded (see [BitArray.Truncate]). The bits at all indexes that
+// exist in both sizes keep their values. Panics if newSize <= 0.
+func (ba *BitArray) Resize(newSize int) {
+	if newSize > ba.size {
+		ba.Grow(newSize - ba.size)
+	} else {
+		ba.Truncate(newSize)
 	}
 }
 
-// MarshalBinary implements the [encoding.BinaryMarshaler] interface.
-func (ba *BitArray) MarshalBinary() ([]byte, error) {
-	b := new(bytes.Buffer)
-	enc := gob.NewEncoder(b)
-	err := enc.Encode(ba.size)
-	if err != nil {
-		return nil, err
+// OrExtend sets ba = ba | other (bitwise OR). If the sizes differ, the shorter operand is
+// zero-extended, i.e. bits beyond its size are treated as 0, and ba grows to the larger size.
+func (ba *BitArray) OrExtend(other *BitArray) {
+	ba.growTo(other.size)
+	for i, x := range other.data {
+		ba.data[i] |= x
 	}
-	err = enc.Encode(ba.data)
-	if err != nil {
-		return nil, err
+}
+
+// AndExtend sets ba = ba & other (bitwise AND). If the sizes differ, the shorter operand is
+// zero-extended, i.e. bits beyond its size are treated as 0, and ba grows to the larger size.
+func (ba *BitArray) AndExtend(other *BitArray) {
+	ba.growTo(other.size)
+	for i, x := range other.data {
+		ba.data[i] &= x
 	}
-	return b.Bytes(), nil
+	clear(ba.data[len(other.data):])
 }
 
-// UnmarshalBinary implements the [encoding.BinaryUnmarshaler] interface.
-func (ba *BitArray) UnmarshalBinary(data []byte) error {
-	b := bytes.NewReader(data)
-	dec := gob.NewDecoder(b)
-	err := dec.Decode(&ba.size)
-	if err != nil {
-		return err
+// XorExtend sets ba = ba ^ other (bitwise XOR). If the sizes differ, the shorter operand is
+// zero-extended, i.e. bits beyond its size are treated as 0, and ba grows to the larger size.
+func (ba *BitArray) XorExtend(other *BitArray) {
+	ba.growTo(other.size)
+	for i, x := range other.data {
+		ba.data[i] ^= x
 	}
-	err = dec.Decode(&ba.data)
+}
+
+func (ba *BitArray) growTo(size int) {
+	if size > ba.size {
+		ba.Grow(size - ba.size)
+	}
+}
+
+// Equal reports whether the two bit arrays are equal.
+func (ba *BitArray) Equal(other *BitArray) bool {
+	if ba.size != other.size {
+		return false
+	}
+	last := len(ba.data) - 1
+	for i := 0; i < last; i++ {
+		if ba.data[i] != other.data[i] {
+			return false
+		}
+	}
+	mask := ba.lastMask()
+	return ba.data[last]&mask == other.data[last]&mask
+}
+
+// EqualValue reports whether the two bit arrays represent the same unsigned integer,
+// i.e. whether they are equal when ignoring leading zeros.
+func (ba *BitArray) EqualValue(other *BitArray) bool {
+	for i := 0; i < max(len(ba.data), len(other.data)); i++ {
+		x, y := ba.byteAt(i), other.byteAt(i)
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare compares the two bit arrays as unsigned integers regardless of their sizes
+// and returns -1 if ba < other, 0 if ba == other, and +1 if ba > other.
+func (ba *BitArray) Compare(other *BitArray) int {
+	for i := max(len(ba.data), len(other.data)) - 1; i >= 0; i-- {
+		x, y := ba.byteAt(i), other.byteAt(i)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Compare compares a and b as unsigned integers like [BitArray.Compare] and, if they
+// have the same value, by their sizes. It returns -1 if a < b, 0 if a and b are equal,
+// and +1 if a > b. This is a total order that can be used with [slices.SortFunc].
+func Compare(a, b *BitArray) int {
+	if c := a.Compare(b); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.size, b.size)
+}
+
+// Count returns the number of set bits.
+func (ba *BitArray) Count() int {
+	cnt := 0
+	data := ba.data
+	for len(data) >= 8 {
+		cnt += bits.OnesCount64(binary.LittleEndian.Uint64(data))
+		data = data[8:]
+	}
+	for _, x := range data {
+		cnt += bits.OnesCount8(x)
+	}
+	return cnt
+}
+
+// Rank returns the number of set bits at indexes [0, idx). Panics if idx < 0 or
+// idx > ba.Size().
+func (ba *BitArray) Rank(idx int) int {
+	if idx != ba.size {
+		ba.checkIdx(idx)
+	}
+	n, r := idx/bitsN, idx%bitsN
+	cnt := 0
+	for _, x := range ba.data[:n] {
+		cnt += bits.OnesCount8(x)
+	}
+	if r > 0 {
+		cnt += bits.OnesCount8(ba.data[n] & (1<<r - 1))
+	}
+	return cnt
+}
+
+// CountRange returns the number of set bits at indexes [start, end).
+// Panics if not 0 <= start <= end <= ba.Size().
+func (ba *BitArray) CountRange(start, end int) int {
+	ba.checkRange(start, end)
+	return ba.Rank(end) - ba.Rank(start)
+}
+
+// SetBitsWithin reports whether all set bits are at indexes [start, end).
+// Panics if not 0 <= start <= end <= ba.Size().
+func (ba *BitArray) SetBitsWithin(start, end int) bool {
+	return ba.CountRange(start, end) == ba.Count()
+}
+
+// Select returns the index of the k-th set bit in ascending order (starting at 0) and
+// reports whether there are more than k set bits.
+func (ba *BitArray) Select(k int) (idx int, ok bool) {
+	if k < 0 {
+		return -1, false
+	}
+	for n, x := range ba.data {
+		if c := bits.OnesCount8(x); k >= c {
+			k -= c
+			continue
+		}
+		for ; k > 0; k-- {
+			x &= x - 1
+		}
+		return n*bitsN + bits.TrailingZeros8(x), true
+	}
+	return -1, false
+}
+
+// SetBits returns the indexes of all set bits in ascending order.
+func (ba *BitArray) SetBits() []int {
+	idx := make([]int, 0, ba.Count())
+	for n, x := range ba.data {
+		for x != 0 {
+			idx = append(idx, n*bitsN+bits.TrailingZeros8(x))
+			x &= x - 1
+		}
+	}
+	return idx
+}
+
+// Sample returns k distinct indexes of set bits chosen at random, using r as the source
+// of randomness, or the indexes of all set bits if fewer than k bits are set. If r is nil,
+// the default source of package math/rand is used. The order of the indexes is
+// unspecified. Panics if k < 0.
+func (ba *BitArray) Sample(r *rand.Rand, k int) []int {
+	if k < 0 {
+		panic("k must be >= 0")
+	}
+	randIntn := rand.Intn
+	if r != nil {
+		randIntn = r.Intn
+	}
+	idx := make([]int, 0, min(k, ba.Count()))
+	seen := 0
+	for n, x := range ba.data {
+		for x != 0 {
+			i := n*bitsN + bits.TrailingZeros8(x)
+			x &= x - 1
+			seen++
+			if len(idx) < k {
+				idx = append(idx, i)
+			} else if j := randIntn(seen); j < k {
+				idx[j] = i
+			}
+		}
+	}
+	return idx
+}
+
+// RunLengths returns the lengths of the runs of equal bits starting at index 0. The
+// first run has the value of the bit at index 0 and the values of the following runs
+// alternate, e.g. the result for "0011101" is [1 1 3 2].
+func (ba *BitArray) RunLengths() []int {
+	var runs []int
+	v := ba.get(0)
+	start := 0
+	for i := 1; i < ba.size; i++ {
+		if ba.get(i) != v {
+			runs = append(runs, i-start)
+			v = !v
+			start = i
+		}
+	}
+	return append(runs, ba.size-start)
+}
+
+// ClearBits returns the indexes of all unset bits in ascending order.
+func (ba *BitArray) ClearBits() []int {
+	idx := make([]int, 0, ba.size-ba.Count())
+	last := len(ba.data) - 1
+	for n, x := range ba.data {
+		x = ^x
+		if n == last {
+			x &= ba.lastMask()
+		}
+		for x != 0 {
+			idx = append(idx, n*bitsN+bits.TrailingZeros8(x))
+			x &= x - 1
+		}
+	}
+	return idx
+}
+
+// AllZero reports whether all bits are 0.
+func (ba *BitArray) AllZero() bool {
+	for _, x := range ba.data {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// AllOne reports whether all bits are 1.
+func (ba *BitArray) AllOne() bool {
+	last := len(ba.data) - 1
+	for _, x := range ba.data[:last] {
+		if x != math.MaxUint8 {
+			return false
+		}
+	}
+	return ba.data[last] == ba.lastMask()
+}
+
+// Any reports whether at least one bit is set.
+func (ba *BitArray) Any() bool {
+	return !ba.AllZero()
+}
+
+// None reports whether no bit is set.
+func (ba *BitArray) None() bool {
+	return ba.AllZero()
+}
+
+// AnyInRange reports whether at least one bit at indexes [start, end) is set.
+// Panics if not 0 <= start <= end <= ba.Size().
+func (ba *BitArray) AnyInRange(start, end int) bool {
+	return ba.CountRange(start, end) > 0
+}
+
+// Labels returns a slice with the length ba.Size() where the element at index i is 0 if
+// the bit at index i is unset, otherwise it is the 1-based number of the run of
+// consecutive set bits that contains the bit, counting from index 0.
+func (ba *BitArray) Labels() []int {
+	labels := make([]int, ba.size)
+	label := 0
+	prev := false
+	for i := range labels {
+		b := ba.get(i)
+		if b {
+			if !prev {
+				label++
+			}
+			labels[i] = label
+		}
+		prev = b
+	}
+	return labels
+}
+
+// HammingDistance returns the number of positions at which the bits of the two bit
+// arrays differ. Panics if the sizes are not equal.
+func (ba *BitArray) HammingDistance(other *BitArray) int {
+	ba.checkSize(other)
+	cnt := 0
+	for i := range ba.data {
+		cnt += bits.OnesCount8(ba.byteAt(i) ^ other.byteAt(i))
+	}
+	return cnt
+}
+
+// Parity returns the XOR of all bits, i.e. it reports whether the number of set bits
+// is odd.
+func (ba *BitArray) Parity() bool {
+	var x uint8
+	for _, y := range ba.data {
+		x ^= y
+	}
+	return bits.OnesCount8(x)%2 == 1
+}
+
+// AppendParityBit appends a parity bit at the high end of the bit array. If even is
+// true, the bit is chosen so that the number of set bits including it is even,
+// otherwise so that it is odd.
+func (ba *BitArray) AppendParityBit(even bool) {
+	ba.Append(ba.Parity() == even)
+}
+
+// Overlaps reports whether at least one bit is set in both bit arrays.
+// Panics if the sizes are not equal.
+func (ba *BitArray) Overlaps(other *BitArray) bool {
+	ba.checkSize(other)
+	for i := range ba.data {
+		if ba.byteAt(i)&other.byteAt(i) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether all bits that are set in other are also set in ba.
+// Panics if the sizes are not equal.
+func (ba *BitArray) Contains(other *BitArray) bool {
+	ba.checkSize(other)
+	for i := range ba.data {
+		if other.byteAt(i)&^ba.byteAt(i) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubsetOf reports whether all bits that are set in ba are also set in other.
+// Panics if the sizes are not equal.
+func (ba *BitArray) IsSubsetOf(other *BitArray) bool {
+	return other.Contains(ba)
+}
+
+// IsProperSubsetOf reports whether ba is a subset of other and not equal to it.
+// Panics if the sizes are not equal.
+func (ba *BitArray) IsProperSubsetOf(other *BitArray) bool {
+	return other.Contains(ba) && !ba.Equal(other)
+}
+
+// Jaccard returns the Jaccard similarity of the two bit arrays, i.e. the number of bits
+// set in both divided by the number of bits set in any of them. If no bit is set in
+// either bit array (the union is empty), 1 is returned by convention. Panics if the
+// sizes are not equal.
+func (ba *BitArray) Jaccard(other *BitArray) float64 {
+	ba.checkSize(other)
+	and, or := 0, 0
+	for i, x := range ba.data {
+		and += bits.OnesCount8(x & other.data[i])
+		or += bits.OnesCount8(x | other.data[i])
+	}
+	if or == 0 {
+		return 1
+	}
+	return float64(and) / float64(or)
+}
+
+// LeadingZeros returns the number of leading unset bits.
+func (ba *BitArray) LeadingZeros() int {
+	cnt := 0
+	for i := len(ba.data) - 1; i >= 0; i-- {
+		if ba.data[i] == 0 {
+			cnt += bitsN
+		} else {
+			cnt += bits.LeadingZeros8(ba.data[i])
+			break
+		}
+	}
+	if x := ba.size % bitsN; x != 0 {
+		cnt -= bitsN - x
+	}
+	return cnt
+}
+
+// TrailingZeros returns the number of trailing unset bits.
+func (ba *BitArray) TrailingZeros() int {
+	cnt := 0
+	for _, x := range ba.data {
+		if x == 0 {
+			cnt += bitsN
+		} else {
+			cnt += bits.TrailingZeros8(x)
+			break
+		}
+	}
+	if cnt > ba.size {
+		cnt = ba.size
+	}
+	return cnt
+}
+
+// LeadingOnes returns the number of leading set bits.
+func (ba *BitArray) LeadingOnes() int {
+	cnt := 0
+	last := len(ba.data) - 1
+	for i := last; i >= 0; i-- {
+		x := ^ba.data[i]
+		if i == last {
+			x &= ba.lastMask()
+		}
+		if x == 0 {
+			cnt += bitsN
+		} else {
+			cnt += bits.LeadingZeros8(x)
+			break
+		}
+	}
+	if x := ba.size % bitsN; x != 0 {
+		cnt -= bitsN - x
+	}
+	return cnt
+}
+
+// TrailingOnes returns the number of trailing set bits.
+func (ba *BitArray) TrailingOnes() int {
+	cnt := 0
+	last := len(ba.data) - 1
+	for i, x := range ba.data {
+		x = ^x
+		if i == last {
+			x &= ba.lastMask()
+		}
+		if x == 0 {
+			cnt += bitsN
+		} else {
+			cnt += bits.TrailingZeros8(x)
+			break
+		}
+	}
+	return min(cnt, ba.size)
+}
+
+// FirstUnset returns the index of the first unset bit and reports whether there is
+// any unset bit.
+func (ba *BitArray) FirstUnset() (int, bool) {
+	return ba.nextUnset(0)
+}
+
+// NextUnset returns the index of the first unset bit at an index >= from and reports
+// whether there is any such bit. Panics if from is out of range.
+func (ba *BitArray) NextUnset(from int) (int, bool) {
+	ba.checkIdx(from)
+	return ba.nextUnset(from)
+}
+
+func (ba *BitArray) nextUnset(from int) (int, bool) {
+	last := len(ba.data) - 1
+	n := from / bitsN
+	x := ^ba.data[n] &^ (1<<(from%bitsN) - 1)
+	for {
+		if n == last {
+			x &= ba.lastMask()
+		}
+		if x != 0 {
+			return n*bitsN + bits.TrailingZeros8(x), true
+		}
+		if n == last {
+			return -1, false
+		}
+		n++
+		x = ^ba.data[n]
+	}
+}
+
+// Allocate sets the first unset bit and returns its index. It reports whether there
+// was an unset bit. Together with [BitArray.Free] the bit array can be used as
+// a simple slot allocator.
+func (ba *BitArray) Allocate() (int, bool) {
+	idx, ok := ba.nextUnset(0)
+	if ok {
+		ba.set(idx)
+	}
+	return idx, ok
+}
+
+// Free unsets the bit at index idx that was set by [BitArray.Allocate].
+// Panics if idx is out of range or the bit is not set (double free).
+func (ba *BitArray) Free(idx int) {
+	ba.checkIdx(idx)
+	if !ba.get(idx) {
+		panic("bit is not set")
+	}
+	ba.unset(idx)
+}
+
+// HighestSet returns the index of the most significant set bit and reports whether
+// there is any set bit.
+func (ba *BitArray) HighestSet() (int, bool) {
+	for i := len(ba.data) - 1; i >= 0; i-- {
+		if x := ba.byteAt(i); x != 0 {
+			return i*bitsN + bits.Len8(x) - 1, true
+		}
+	}
+	return -1, false
+}
+
+// LowestSet returns the index of the least significant set bit and reports whether
+// there is any set bit. Unlike [BitArray.TrailingZeros], it distinguishes between
+// no set bit and only the bit at index 0 set.
+func (ba *BitArray) LowestSet() (int, bool) {
+	for i, x := range ba.data {
+		if x != 0 {
+			return i*bitsN + bits.TrailingZeros8(x), true
+		}
+	}
+	return -1, false
+}
+
+// BitLen returns the index of the most significant set bit plus 1 or 0 if no bit
+// is set, i.e. the bit length of the bit array as an unsigned integer.
+func (ba *BitArray) BitLen() int {
+	idx, _ := ba.HighestSet()
+	return idx + 1
+}
+
+// Trim returns a new BitArray without the leading unset bits, i.e. with the size
+// [BitArray.BitLen]. Because the size must be > 0, the result for a bit array with
+// no set bit is a bit array with the size 1 and the bit at index 0 unset.
+func (ba *BitArray) Trim() *BitArray {
+	return Slice(ba, 0, max(ba.BitLen(), 1))
+}
+
+// SetBitsBounds returns the smallest range [start, end) that contains all set bits
+// and reports whether there is any set bit.
+func (ba *BitArray) SetBitsBounds() (start, end int, ok bool) {
+	lo, ok := ba.LowestSet()
+	if !ok {
+		return 0, 0, false
+	}
+	hi, _ := ba.HighestSet()
+	return lo, hi + 1, true
+}
+
+// MedianSet returns the index of the middle set bit, i.e. the (Count()/2)-th set bit
+// in ascending order, and reports whether there is any set bit.
+func (ba *BitArray) MedianSet() (int, bool) {
+	return ba.Select(ba.Count() / 2)
+}
+
+// BucketCounts divides the bit array into numBuckets buckets of equal width and
+// returns the number of set bits in each bucket. The last bucket also contains the
+// remaining bits if the size is not divisible by numBuckets. Panics if numBuckets <= 0.
+func (ba *BitArray) BucketCounts(numBuckets int) []int {
+	if numBuckets <= 0 {
+		panic("number of buckets must be > 0")
+	}
+	width := ba.size / numBuckets
+	counts := make([]int, numBuckets)
+	for i := 0; i < ba.size; i++ {
+		if ba.get(i) {
+			b := numBuckets - 1
+			if width > 0 && i/width < b {
+				b = i / width
+			}
+			counts[b]++
+		}
+	}
+	return counts
+}
+
+// Size returns the size of the bit array.
+func (ba *BitArray) Size() int {
+	return ba.size
+}
+
+// String returns a string representation of the bit array.
+func (ba *BitArray) String() string {
+	baLen := len(ba.data)
+	n := ba.size % bitsN
+	if n == 0 {
+		n = bitsN
+	}
+	s := fmt.Sprintf("%0*b", n, ba.data[baLen-1])
+	if baLen > 1 {
+		for i := baLen - 2; i >= 0; i-- {
+			s += fmt.Sprintf("%0*b", bitsN, ba.data[i])
+		}
+	}
+	return s
+}
+
+// Grouped returns the string representation of the bit array (see [BitArray.String]) with
+// sep inserted every groupSize bits, counting from index 0 (the right), e.g. "11 0000 0101"
+// for groupSize 4 and sep " ". With sep " " the result can be read back by [Parse].
+// ([BitArray.Format] implements [fmt.Formatter] and thus cannot be used for this.)
+// Panics if groupSize <= 0.
+func (ba *BitArray) Grouped(groupSize int, sep string) string {
+	if groupSize <= 0 {
+		panic("group size must be > 0")
+	}
+	s := ba.String()
+	var sb strings.Builder
+	first := len(s) % groupSize
+	if first == 0 {
+		first = groupSize
+	}
+	sb.WriteString(s[:first])
+	for i := first; i < len(s); i += groupSize {
+		sb.WriteString(sep)
+		sb.WriteString(s[i : i+groupSize])
+	}
+	return sb.String()
+}
+
+// StringMSB returns a string representation of the bit array where, unlike [BitArray.String],
+// the leftmost character is the bit at index 0. It is the inverse of [ParseMSB].
+func (ba *BitArray) StringMSB() string {
+	b := make([]byte, ba.size)
+	for i := range b {
+		if ba.get(i) {
+			b[i] = '1'
+		} else {
+			b[i] = '0'
+		}
+	}
+	return string(b)
+}
+
+// Report returns a human-readable comparison of the two bit arrays for debugging. It
+// contains the sizes, the counts, the Hamming distance (if the sizes are equal), the
+// index of the first differing bit, and both bit arrays aligned at index 0 with the
+// differing bits marked.
+func (ba *BitArray) Report(other *BitArray) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "size:             %d, %d\n", ba.size, other.size)
+	fmt.Fprintf(&sb, "count:            %d, %d\n", ba.Count(), other.Count())
+	if ba.size == other.size {
+		fmt.Fprintf(&sb, "hamming distance: %d\n", ba.HammingDistance(other))
+	} else {
+		sb.WriteString("hamming distance: n/a\n")
+	}
+	n := max(ba.size, other.size)
+	marks := make([]byte, n)
+	first := -1
+	for i := 0; i < n; i++ {
+		if i >= ba.size || i >= other.size || ba.get(i) != other.get(i) {
+			marks[n-1-i] = '^'
+			if first < 0 {
+				first = i
+			}
+		} else {
+			marks[n-1-i] = ' '
+		}
+	}
+	if first < 0 {
+		sb.WriteString("first difference: none\n")
+	} else {
+		fmt.Fprintf(&sb, "first difference: %d\n", first)
+	}
+	fmt.Fprintf(&sb, "%*s\n", n, ba.String())
+	fmt.Fprintf(&sb, "%*s\n", n, other.String())
+	sb.WriteString(strings.TrimRight(string(marks), " "))
+	return sb.String()
+}
+
+// Debug returns a description of the underlying storage for debugging. It contains the
+// size and, for each byte, its index, its value in hexadecimal and binary, and the bit
+// indexes it holds. For the last byte it also tells whether the unused bits are 0 (masked)
+// or not (unmasked).
+func (ba *BitArray) Debug() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "size: %d, bytes: %d", ba.size, len(ba.data))
+	for i, x := range ba.data {
+		lo := i * bitsN
+		hi := min(lo+bitsN, ba.size) - 1
+		fmt.Fprintf(&sb, "\n[%d] 0x%02x %08b bits %d-%d", i, x, x, lo, hi)
+		if i == len(ba.data)-1 && ba.size%bitsN != 0 {
+			if x&^ba.lastMask() == 0 {
+				sb.WriteString(", padding masked")
+			} else {
+				sb.WriteString(", padding unmasked")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Dump writes a hex dump of the underlying bytes (see [BitArray.Bytes]) to w. Each line
+// contains the offset of the first byte, up to 8 bytes in hexadecimal, and the range of
+// bit indexes they hold. The unused bits of the last byte are written as 0.
+func (ba *BitArray) Dump(w io.Writer) error {
+	const perLine = 8
+	for off := 0; off < len(ba.data); off += perLine {
+		end := min(off+perLine, len(ba.data))
+		var sb strings.Builder
+		for i := off; i < end; i++ {
+			x := ba.data[i]
+			if i == len(ba.data)-1 {
+				x &= ba.lastMask()
+			}
+			if i > off {
+				sb.WriteByte(' ')
+			}
+			fmt.Fprintf(&sb, "%02x", x)
+		}
+		lo, hi := off*bitsN, min(end*bitsN, ba.size)-1
+		if _, err := fmt.Fprintf(w, "%08x  %-*s  bits %d-%d\n", off, 3*perLine-1, sb.String(), lo, hi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ToHex returns the bit array as a string of hexadecimal digits with lower-case
+// letters. The last digit holds the bits at indexes 0-3 and so on; the first digit
+// holds the remaining bits if the size is not divisible by 4.
+func (ba *BitArray) ToHex() string {
+	return ba.radixString(4, false)
+}
+
+// Key returns a compact string that is unique for the size and the bits of the bit
+// array, so it can be used as a map key. It is not meant to be human-readable.
+func (ba *BitArray) Key() string {
+	b := binary.AppendUvarint(nil, uint64(ba.size))
+	b = append(b, ba.data...)
+	b[len(b)-1] &= ba.lastMask()
+	return string(b)
+}
+
+// Format implements the [fmt.Formatter] interface. The verbs %b, %s, and %v format
+// the bit array in binary like [BitArray.String], %o in octal, and %x and %X in
+// hexadecimal with lower-case and upper-case letters respectively. Width, precision
+// (minimum number of digits), and the flags '-', '0', and '#' (adds the prefix 0b, 0,
+// 0x, or 0X) are supported like for integers. The verb %q formats the result of
+// [BitArray.String] as a quoted string.
+func (ba *BitArray) Format(f fmt.State, verb rune) {
+	var s, prefix string
+	switch verb {
+	case 'b', 's', 'v':
+		s, prefix = ba.String(), "0b"
+	case 'o':
+		s, prefix = ba.radixString(3, false), "0"
+	case 'x':
+		s, prefix = ba.radixString(4, false), "0x"
+	case 'X':
+		s, prefix = ba.radixString(4, true), "0X"
+	case 'q':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), ba.String())
+		return
+	default:
+		fmt.Fprintf(f, "%%!%c(*bitarray.BitArray=%s)", verb, ba.String())
+		return
+	}
+	p, hasPrec := f.Precision()
+	if hasPrec && len(s) < p {
+		s = strings.Repeat("0", p-len(s)) + s
+	}
+	if !f.Flag('#') || verb == 's' || verb == 'v' {
+		prefix = ""
+	}
+	if w, ok := f.Width(); ok && len(prefix)+len(s) < w {
+		n := w - len(prefix) - len(s)
+		switch {
+		case f.Flag('-'):
+			s += strings.Repeat(" ", n)
+		case f.Flag('0') && !hasPrec:
+			s = strings.Repeat("0", n) + s
+		default:
+			prefix = strings.Repeat(" ", n) + prefix
+		}
+	}
+	io.WriteString(f, prefix+s)
+}
+
+// radixString returns the bit array as a string of digits formed from groups of k bits
+// starting at index 0.
+func (ba *BitArray) radixString(k int, upper bool) string {
+	digits := "0123456789abcdef"
+	if upper {
+		digits = "0123456789ABCDEF"
+	}
+	n := (ba.size + k - 1) / k
+	b := make([]byte, n)
+	for d := 0; d < n; d++ {
+		v := 0
+		for i := min(d*k+k, ba.size) - 1; i >= d*k; i-- {
+			v <<= 1
+			if ba.get(i) {
+				v |= 1
+			}
+		}
+		b[n-1-d] = digits[v]
+	}
+	return string(b)
+}
+
+func (ba *BitArray) checkIdx(idx int) {
+	if idx < 0 || idx >= ba.size {
+		panic("index out of range")
+	}
+}
+
+func (ba *BitArray) checkRange(start, end int) {
+	if start < 0 || start > end || end > ba.size {
+		panic("range out of bounds")
+	}
+}
+
+func (ba *BitArray) checkSize(other *BitArray) {
+	if ba.size != other.size {
+		panic("bit array sizes must be equal")
+	}
+}
+
+// binaryVersion is the first byte of the binary format. The gob-based format of
+// earlier versions never starts with a byte in the range 0x80-0xf7.
+const binaryVersion = 0x81
+
+// MarshalBinary implements the [encoding.BinaryMarshaler] interface. The data consists
+// of a version byte, the size as an unsigned varint, and the bytes in the format
+// returned by [BitArray.Bytes].
+func (ba *BitArray) MarshalBinary() ([]byte, error) {
+	return ba.appendDense([]byte{binaryVersion}), nil
+}
+
+// UnmarshalBinary implements the [encoding.BinaryUnmarshaler] interface. It also
+// accepts the gob-based format of earlier versions.
+func (ba *BitArray) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != binaryVersion {
+		return ba.unmarshalGob(data)
+	}
+	result, err := decodeDense(data[1:])
+	if err != nil {
+		return err
+	}
+	*ba = *result
+	return nil
+}
+
+func (ba *BitArray) unmarshalGob(data []byte) error {
+	b := bytes.NewReader(data)
+	dec := gob.NewDecoder(b)
+	var size int
+	err := dec.Decode(&size)
+	if err != nil {
+		return err
+	}
+	var sl []uint8
+	err = dec.Decode(&sl)
+	if err != nil {
+		return err
+	}
+	if size <= 0 {
+		return fmt.Errorf("size must be > 0: %d", size)
+	}
+	result, err := FromBytesLE(size, sl)
+	if err != nil {
+		return err
+	}
+	*ba = *result
+	return nil
+}
+
+// GobEncode implements the [gob.GobEncoder] interface. The data is the same as the one
+// returned by [BitArray.MarshalBinary].
+func (ba *BitArray) GobEncode() ([]byte, error) {
+	return ba.MarshalBinary()
+}
+
+// GobDecode implements the [gob.GobDecoder] interface. The data is decoded like with
+// [BitArray.UnmarshalBinary].
+func (ba *BitArray) GobDecode(data []byte) error {
+	return ba.UnmarshalBinary(data)
+}
+
+// MarshalText implements the [encoding.TextMarshaler] interface.
+// The text is the same as the one returned by [BitArray.String].
+func (ba *BitArray) MarshalText() ([]byte, error) {
+	return []byte(ba.String()), nil
+}
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface.
+// The text is parsed like with [Parse].
+func (ba *BitArray) UnmarshalText(text []byte) error {
+	s := string(text)
+	if strings.ReplaceAll(s, " ", "") == "" {
+		return errors.New("empty text")
+	}
+	result, err := Parse(s)
 	if err != nil {
 		return err
 	}
+	*ba = *result
 	return nil
 }
 
+// MarshalJSON implements the [json.Marshaler] interface.
+// The bit array is encoded as a JSON string in the format of [BitArray.String].
+func (ba *BitArray) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ba.String())
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+// The JSON value must be a string that is parsed like with [Parse].
+// A JSON null leaves ba unchanged.
+func (ba *BitArray) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("JSON value must be a string: %s", data)
+	}
+	return ba.UnmarshalText([]byte(s))
+}
+
 // Slice returns a new BitArray with the bits from ba at indexes [start, end).
+// Panics if start < 0, end > ba.Size(), or start >= end.
 func Slice(ba *BitArray, start, end int) *BitArray {
-	ba.checkIdx(start)
-	ba.checkIdx(end - 1)
+	if start < 0 || end > ba.size || start > end {
+		panic("slice bounds out of range")
+	}
+	if start == end {
+		panic("empty slice")
+	}
 	result := New(end - start)
 	idx := 0
 	for i := start; i < end; i++ {
@@ -427,6 +1748,17 @@ func Slice(ba *BitArray, start, end int) *BitArray {
 	return result
 }
 
+// Sub returns a new BitArray with the bits from ba at indexes [start, end) like [Slice].
+// If end < 0, it is relative to the size, i.e. ba.Size() + end is used, so Sub(2, -1)
+// drops the bits at index 0, 1, and ba.Size()-1. Panics if the resulting range is
+// empty or out of bounds.
+func (ba *BitArray) Sub(start, end int) *BitArray {
+	if end < 0 {
+		end += ba.size
+	}
+	return Slice(ba, start, end)
+}
+
 // Concat returns a new BitArray with the bits from ba1 and ba2 concatenated.
 func Concat(ba1, ba2 *BitArray) *BitArray {
 	ba := New(ba1.size + ba2.size)
@@ -449,13 +1781,310 @@ func Concat(ba1, ba2 *BitArray) *BitArray {
 	return ba
 }
 
+// ConcatAll returns a new BitArray with the bits from all given bit arrays concatenated
+// like with [Concat], i.e. the last bit array gets the lowest indexes and
+// ConcatAll(a, b, c) equals Concat(Concat(a, b), c). Panics if arrs is empty.
+func ConcatAll(arrs ...*BitArray) *BitArray {
+	if len(arrs) == 0 {
+		panic("no bit arrays")
+	}
+	size := 0
+	for _, a := range arrs {
+		size += a.size
+	}
+	ba := New(size)
+	idx := 0
+	for i := len(arrs) - 1; i >= 0; i-- {
+		a := arrs[i]
+		if idx%bitsN == 0 {
+			copy(ba.data[idx/bitsN:], a.data)
+		} else {
+			for j := 0; j < a.size; j++ {
+				if a.get(j) {
+					ba.set(idx + j)
+				}
+			}
+		}
+		idx += a.size
+	}
+	return ba
+}
+
+// Split returns the bit array split into new bit arrays with chunkSize bits each, starting
+// at index 0; the last one has fewer bits if ba.Size() is not divisible by chunkSize.
+// The bit array can be restored with [ConcatAll] with the chunks in reverse order.
+// Panics if chunkSize <= 0.
+func (ba *BitArray) Split(chunkSize int) []*BitArray {
+	if chunkSize <= 0 {
+		panic("chunk size must be > 0")
+	}
+	chunks := make([]*BitArray, 0, (ba.size+chunkSize-1)/chunkSize)
+	for i := 0; i < ba.size; i += chunkSize {
+		chunks = append(chunks, Slice(ba, i, min(i+chunkSize, ba.size)))
+	}
+	return chunks
+}
+
+// Interleave returns a new BitArray with the bits of the given bit arrays interleaved,
+// i.e. the bit at index i of arrs[j] gets the index i*len(arrs)+j. This is the inverse
+// of [BitArray.Deinterleave]. Panics if arrs is empty or the sizes are not equal.
+func Interleave(arrs ...*BitArray) *BitArray {
+	if len(arrs) == 0 {
+		panic("no bit arrays")
+	}
+	for _, a := range arrs[1:] {
+		arrs[0].checkSize(a)
+	}
+	n := len(arrs)
+	ba := New(arrs[0].size * n)
+	for j, a := range arrs {
+		for i := 0; i < a.size; i++ {
+			if a.get(i) {
+				ba.set(i*n + j)
+			}
+		}
+	}
+	return ba
+}
+
+// Deinterleave returns n new BitArrays where the bit at index i of the j-th one is
+// the bit of ba at index i*n+j. This is the inverse of [Interleave].
+// Panics if n <= 0 or ba.Size() is not divisible by n.
+func (ba *BitArray) Deinterleave(n int) []*BitArray {
+	if n <= 0 || ba.size%n != 0 {
+		panic("invalid number of bit arrays")
+	}
+	arrs := make([]*BitArray, n)
+	for j := range arrs {
+		a := New(ba.size / n)
+		for i := 0; i < a.size; i++ {
+			if ba.get(i*n + j) {
+				a.set(i)
+			}
+		}
+		arrs[j] = a
+	}
+	return arrs
+}
+
+// And returns a new BitArray with a & b (bitwise AND). Panics if the sizes are not equal.
+func And(a, b *BitArray) *BitArray {
+	a.checkSize(b)
+	ba := Clone(a)
+	ba.And(b)
+	return ba
+}
+
+// Or returns a new BitArray with a | b (bitwise OR). Panics if the sizes are not equal.
+func Or(a, b *BitArray) *BitArray {
+	a.checkSize(b)
+	ba := Clone(a)
+	ba.Or(b)
+	return ba
+}
+
+// Xor returns a new BitArray with a ^ b (bitwise XOR). Panics if the sizes are not equal.
+func Xor(a, b *BitArray) *BitArray {
+	a.checkSize(b)
+	ba := Clone(a)
+	ba.Xor(b)
+	return ba
+}
+
+// AndNot returns a new BitArray with a &^ b (bit clear). Panics if the sizes are not equal.
+func AndNot(a, b *BitArray) *BitArray {
+	a.checkSize(b)
+	ba := Clone(a)
+	ba.AndNot(b)
+	return ba
+}
+
+// Union returns a new BitArray with the bits set in a or b. Panics if the sizes are not equal.
+func Union(a, b *BitArray) *BitArray {
+	return Or(a, b)
+}
+
+// Intersection returns a new BitArray with the bits set in both a and b.
+// Panics if the sizes are not equal.
+func Intersection(a, b *BitArray) *BitArray {
+	return And(a, b)
+}
+
+// Difference returns a new BitArray with the bits set in a but not in b.
+// Panics if the sizes are not equal.
+func Difference(a, b *BitArray) *BitArray {
+	return AndNot(a, b)
+}
+
+// SymmetricDifference returns a new BitArray with the bits set in either a or b
+// but not in both. Panics if the sizes are not equal.
+func SymmetricDifference(a, b *BitArray) *BitArray {
+	return Xor(a, b)
+}
+
+// PopCounts returns the number of set bits of each bit array.
+func PopCounts(arrs []*BitArray) []int {
+	counts := make([]int, len(arrs))
+	for i, ba := range arrs {
+		counts[i] = ba.Count()
+	}
+	return counts
+}
+
+// OrReduce returns a new BitArray with the bitwise OR of all bit arrays.
+// Panics if no bit array is given or the sizes are not equal.
+func OrReduce(arrs ...*BitArray) *BitArray {
+	if len(arrs) == 0 {
+		panic("no bit arrays")
+	}
+	ba := Clone(arrs[0])
+	for _, other := range arrs[1:] {
+		ba.Or(other)
+	}
+	return ba
+}
+
+// AndReduce returns a new BitArray with the bitwise AND of all bit arrays.
+// Panics if no bit array is given or the sizes are not equal.
+func AndReduce(arrs ...*BitArray) *BitArray {
+	if len(arrs) == 0 {
+		panic("no bit arrays")
+	}
+	ba := Clone(arrs[0])
+	for _, other := range arrs[1:] {
+		ba.And(other)
+	}
+	return ba
+}
+
+// XorReduce returns a new BitArray with the bitwise XOR of all bit arrays.
+// Panics if no bit array is given or the sizes are not equal.
+func XorReduce(arrs ...*BitArray) *BitArray {
+	if len(arrs) == 0 {
+		panic("no bit arrays")
+	}
+	ba := Clone(arrs[0])
+	for _, other := range arrs[1:] {
+		ba.Xor(other)
+	}
+	return ba
+}
+
+// Ternary returns a new BitArray where each bit is the result of the 3-input lookup
+// table lut applied to the bits of a, b, and c at the same index. The result bit is
+// bit number 4*a + 2*b + c of lut, e.g. lut 0x80 computes a & b & c, 0xE8 the majority,
+// and 0xCA a ? b : c. Panics if the sizes are not equal.
+func Ternary(a, b, c *BitArray, lut uint8) *BitArray {
+	a.checkSize(b)
+	a.checkSize(c)
+	ba := New(a.size)
+	for i := range ba.data {
+		for k := 0; k < 8; k++ {
+			if lut&(1<<k) == 0 {
+				continue
+			}
+			x, y, z := a.data[i], b.data[i], c.data[i]
+			if k&4 == 0 {
+				x = ^x
+			}
+			if k&2 == 0 {
+				y = ^y
+			}
+			if k&1 == 0 {
+				z = ^z
+			}
+			ba.data[i] |= x & y & z
+		}
+	}
+	ba.Normalize()
+	return ba
+}
+
+// ReadFrom creates a new BitArray with size bits from the bytes read from r in the
+// format returned by [BitArray.Bytes]. Returns [io.ErrUnexpectedEOF] if r ends before
+// all bytes are read and an error if one of the unused bits of the last byte is set.
+// Panics if size <= 0.
+func ReadFrom(r io.Reader, size int) (*BitArray, error) {
+	b := make([]byte, dataLen(size))
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return FromBytesLE(size, b)
+}
+
+// WriteTo implements the [io.WriterTo] interface. It writes the bytes in the format
+// returned by [BitArray.Bytes] to w. They can be read with [ReadFrom].
+func (ba *BitArray) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(ba.data)
+	return int64(n), err
+}
+
+// Outer returns the outer (tensor) product of a and b as a new BitArray with size
+// a.Size() * b.Size(). The bit at index i*b.Size() + j is set if the bit at index i
+// of a and the bit at index j of b are set, i.e. the result consists of a.Size()
+// blocks of b.Size() bits.
+func Outer(a, b *BitArray) *BitArray {
+	ba := New(a.size * b.size)
+	for i := 0; i < a.size; i++ {
+		if !a.get(i) {
+			continue
+		}
+		for j := 0; j < b.size; j++ {
+			if b.get(j) {
+				ba.set(i*b.size + j)
+			}
+		}
+	}
+	return ba
+}
+
+// KroneckerOr returns a new BitArray with size a.Size() * b.Size() where the bit at
+// index i*b.Size() + j is set if the bit at index i of a or the bit at index j of b
+// is set. The layout is the same as for [Outer].
+func KroneckerOr(a, b *BitArray) *BitArray {
+	ba := New(a.size * b.size)
+	for i := 0; i < a.size; i++ {
+		ai := a.get(i)
+		for j := 0; j < b.size; j++ {
+			if ai || b.get(j) {
+				ba.set(i*b.size + j)
+			}
+		}
+	}
+	return ba
+}
+
+// Convolve returns the convolution of a and b with the bits as integer coefficients.
+// The element at index k of the result with length a.Size() + b.Size() - 1 is the
+// number of pairs (i, j) with i + j = k where the bits at index i of a and at index j
+// of b are set.
+func Convolve(a, b *BitArray) []int {
+	result := make([]int, a.size+b.size-1)
+	for i := 0; i < a.size; i++ {
+		if !a.get(i) {
+			continue
+		}
+		for j := 0; j < b.size; j++ {
+			if b.get(j) {
+				result[i+j]++
+			}
+		}
+	}
+	return result
+}
+
 // FromBytes creates a new BitArray from the byte slice. Panics if len(bytes) == 0.
 func FromBytes(bytes []byte) *BitArray {
 	if len(bytes) == 0 {
 		panic("size must be > 0")
 	}
-	slices.Reverse(bytes)
-	return &BitArray{size: len(bytes) * bitsN, data: bytes}
+	data := makeData(len(bytes))
+	copy(data, bytes)
+	slices.Reverse(data)
+	return &BitArray{size: len(bytes) * bitsN, data: data}
 }
 
 // ToBytes returns the bit array as a byte slice.
@@ -464,3 +2093,190 @@ func (ba *BitArray) ToBytes() []byte {
 	slices.Reverse(bytes)
 	return bytes
 }
+
+// BigInt returns the bit array as a non-negative [big.Int] with bit i of the
+// integer equal to the bit at index i.
+func (ba *BitArray) BigInt() *big.Int {
+	b := make([]byte, len(ba.data))
+	copy(b, ba.data)
+	slices.Reverse(b)
+	return new(big.Int).SetBytes(b)
+}
+
+// FromBigInt creates a new BitArray with size bits from the lowest size bits of v.
+// Panics if size <= 0 or v < 0.
+func FromBigInt(size int, v *big.Int) *BitArray {
+	if v.Sign() < 0 {
+		panic("value must be >= 0")
+	}
+	ba := New(size)
+	b := v.Bytes()
+	slices.Reverse(b)
+	copy(ba.data, b)
+	ba.Normalize()
+	return ba
+}
+
+// Normalize sets the unused bits of the last byte of the underlying storage to 0.
+// All methods expect these bits to be 0 and keep them 0, so Normalize is only needed
+// after the storage was manipulated directly.
+func (ba *BitArray) Normalize() {
+	ba.data[len(ba.data)-1] &= ba.lastMask()
+}
+
+// byteAt returns the byte at index i of the storage with the unused bits of the last
+// byte masked out, or 0 if i >= len(ba.data).
+func (ba *BitArray) byteAt(i int) uint8 {
+	switch {
+	case i < len(ba.data)-1:
+		return ba.data[i]
+	case i == len(ba.data)-1:
+		return ba.data[i] & ba.lastMask()
+	}
+	return 0
+}
+
+func (ba *BitArray) lastMask() uint8 {
+	if x := ba.size % bitsN; x != 0 {
+		return 1<<x - 1
+	}
+	return math.MaxUint8
+}
+
+// Digits returns the digits of the bit array as an unsigned integer (see [BitArray.BigInt])
+// in the given base with the least significant digit first. Returns an error if
+// base < 2 or base > 36.
+func (ba *BitArray) Digits(base int) ([]int, error) {
+	if base < 2 || base > 36 {
+		return nil, fmt.Errorf("base out of range: %d", base)
+	}
+	v := ba.BigInt()
+	if v.Sign() == 0 {
+		return []int{0}, nil
+	}
+	b := big.NewInt(int64(base))
+	m := new(big.Int)
+	var digits []int
+	for v.Sign() > 0 {
+		v.DivMod(v, b, m)
+		digits = append(digits, int(m.Int64()))
+	}
+	return digits, nil
+}
+
+// Uint64 returns the bit array as an unsigned integer with bit i of the integer
+// equal to the bit at index i. Returns an error if ba.Size() > 64.
+func (ba *BitArray) Uint64() (uint64, error) {
+	if ba.size > 64 {
+		return 0, fmt.Errorf("size must be <= 64: %d", ba.size)
+	}
+	var v uint64
+	for i, x := range ba.data {
+		v |= uint64(x) << (i * bitsN)
+	}
+	return v, nil
+}
+
+// FromUint64 creates a new BitArray with size bits from the lowest size bits of v.
+// Panics if size <= 0 or size > 64.
+func FromUint64(size int, v uint64) *BitArray {
+	if size > 64 {
+		panic("size must be <= 64")
+	}
+	ba := New(size)
+	for i := range ba.data {
+		ba.data[i] = uint8(v >> (i * bitsN))
+	}
+	ba.Normalize()
+	return ba
+}
+
+// Keep returns a new BitArray with the same size where only the bits of ba at the
+// given indexes are retained and all other bits are 0. Panics if one of the indexes
+// is out of range.
+func (ba *BitArray) Keep(idx ...int) *BitArray {
+	result := New(ba.size)
+	for _, i := range idx {
+		ba.checkIdx(i)
+		if ba.get(i) {
+			result.set(i)
+		}
+	}
+	return result
+}
+
+// Drop returns a new BitArray with the same size where the bits at the given indexes
+// are 0 and all other bits are retained from ba. Panics if one of the indexes is out
+// of range.
+func (ba *BitArray) Drop(idx ...int) *BitArray {
+	result := Clone(ba)
+	for _, i := range idx {
+		ba.checkIdx(i)
+		result.unset(i)
+	}
+	return result
+}
+
+// Bytes returns a copy of the underlying bytes in little-endian order, i.e. byte 0
+// holds the bits at indexes 0-7. The unused bits of the last byte are 0.
+func (ba *BitArray) Bytes() []byte {
+	b := make([]byte, len(ba.data))
+	copy(b, ba.data)
+	return b
+}
+
+// BytesMSB returns the bits packed into bytes like [BitArray.Bytes], but with the bit
+// order within each byte reversed: bit 7 (the most significant bit) of byte 0 holds the
+// bit at index 0, bit 6 the bit at index 1, and so on (MSB-first order as used by many
+// network protocols). The unused low bits of the last byte are 0.
+func (ba *BitArray) BytesMSB() []byte {
+	b := make([]byte, len(ba.data))
+	for i, x := range ba.data {
+		b[i] = bits.Reverse8(x)
+	}
+	return b
+}
+
+// ForEachByte calls f for each byte in the format returned by [BitArray.Bytes] with
+// its index until f returns false.
+func (ba *BitArray) ForEachByte(f func(i int, b byte) bool) {
+	last := len(ba.data) - 1
+	for i, x := range ba.data {
+		if i == last {
+			x &= ba.lastMask()
+		}
+		if !f(i, x) {
+			return
+		}
+	}
+}
+
+// FromBytesMSB creates a new BitArray with size bits from the byte slice in the format
+// returned by [BitArray.BytesMSB], i.e. with bit 7 of byte 0 as the bit at index 0.
+// Returns an error if the length of b does not match size or if one of the unused bits
+// of the last byte is set. Panics if size <= 0.
+func FromBytesMSB(size int, b []byte) (*BitArray, error) {
+	r := make([]byte, len(b))
+	for i, x := range b {
+		r[i] = bits.Reverse8(x)
+	}
+	return FromBytesLE(size, r)
+}
+
+// FromBytesLE creates a new BitArray with size bits from the byte slice in the format
+// returned by [BitArray.Bytes]. Returns an error if the length of b does not match
+// size or if one of the unused bits of the last byte is set. Panics if size <= 0.
+func FromBytesLE(size int, b []byte) (*BitArray, error) {
+	if size <= 0 {
+		panic("size must be > 0")
+	}
+	if n := dataLen(size); len(b) != n {
+		return nil, fmt.Errorf("byte slice length must be %d: %d", n, len(b))
+	}
+	if r := size % bitsN; r != 0 && b[len(b)-1]>>r != 0 {
+		return nil, fmt.Errorf("unused bits must be 0")
+	}
+	ba := New(size)
+	copy(ba.data, b)
+	return ba, nil
+}
@@ -1,7 +1,18 @@
 package bitarray
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -66,6 +77,257 @@ func TestMustParsePanic(t *testing.T) {
 	t.Error("did not panic")
 }
 
+func TestGrouped(t *testing.T) {
+	tests := []struct {
+		s         string
+		groupSize int
+		sep       string
+		want      string
+	}{
+		{"01010101", 4, " ", "0101 0101"},
+		{"1100000101", 4, " ", "11 0000 0101"},
+		{"1100000101", 8, "_", "11_00000101"},
+		{"0101", 4, " ", "0101"},
+		{"101", 4, " ", "101"},
+		{"0101010101010101", 8, " ", "01010101 01010101"},
+		{"10101010101010101", 8, "", "10101010101010101"},
+		{"1100000101", 3, "-", "1-100-000-101"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got := ba.Grouped(test.groupSize, test.sep)
+		if got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if test.sep == " " && !MustParse(got).Equal(ba) {
+			t.Errorf("%d: %q does not parse back", i, got)
+		}
+	}
+}
+
+func TestGroupedPanic(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Grouped(0, " ")
+	t.Error("did not panic")
+}
+
+func TestParseMSB(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"1000", "0001"},
+		{"0001", "1000"},
+		{"1100000101", "1010000011"},
+		{"11 0000 0101", "1010000011"},
+		{"1", "1"},
+	}
+	for i, test := range tests {
+		ba, err := ParseMSB(test.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got, want := ba.StringMSB(), strings.ReplaceAll(test.s, " ", ""); got != want {
+			t.Errorf("%d: got %q, want %q", i, got, want)
+		}
+	}
+	if ba, _ := ParseMSB("1000"); !ba.Get(0) {
+		t.Error("index 0 not set")
+	}
+	if _, err := ParseMSB("012"); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestRangeMask(t *testing.T) {
+	tests := []struct {
+		size, lo, hi int
+		want         string
+	}{
+		{10, 2, 5, "0000111100"},
+		{10, 0, 9, "1111111111"},
+		{10, 9, 9, "1000000000"},
+		{4, 0, 0, "0001"},
+		{16, 4, 11, "0000111111110000"},
+	}
+	for i, test := range tests {
+		if got := RangeMask(test.size, test.lo, test.hi).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestRangeMaskPanic(t *testing.T) {
+	tests := []struct {
+		size, lo, hi int
+	}{
+		{10, -1, 5},
+		{10, 5, 4},
+		{10, 2, 10},
+		{0, 0, 0},
+	}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			RangeMask(test.size, test.lo, test.hi)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestLowHighMask(t *testing.T) {
+	tests := []struct {
+		size, k   int
+		low, high string
+	}{
+		{10, 3, "0000000111", "1110000000"},
+		{10, 0, "0000000000", "0000000000"},
+		{10, 10, "1111111111", "1111111111"},
+		{16, 9, "0000000111111111", "1111111110000000"},
+		{1, 1, "1", "1"},
+	}
+	for i, test := range tests {
+		if got := LowMask(test.size, test.k).String(); got != test.low {
+			t.Errorf("%d: got %q, want %q", i, got, test.low)
+		}
+		if got := HighMask(test.size, test.k).String(); got != test.high {
+			t.Errorf("%d: got %q, want %q", i, got, test.high)
+		}
+	}
+}
+
+func TestLowHighMaskPanic(t *testing.T) {
+	tests := []struct {
+		size, k int
+	}{
+		{10, -1},
+		{10, 11},
+		{0, 0},
+	}
+	for i, test := range tests {
+		for _, f := range []func(size, k int) *BitArray{LowMask, HighMask} {
+			func() {
+				defer func() { recover() }()
+				f(test.size, test.k)
+				t.Errorf("%d: did not panic", i)
+			}()
+		}
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		size int
+		s    string
+		want string
+	}{
+		{8, "a5", "10100101"},
+		{8, "A5", "10100101"},
+		{10, "3ff", "1111111111"},
+		{10, "0 3F F", "1111111111"},
+		{10, "5", "0000000101"},
+		{3, "7", "111"},
+		{1, "0000001", "1"},
+		{13, "1abc", "1101010111100"},
+	}
+	for i, test := range tests {
+		ba, err := ParseHex(test.size, test.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestParseHexError(t *testing.T) {
+	tests := []struct {
+		size int
+		s    string
+	}{
+		{8, ""},
+		{8, "  "},
+		{8, "g0"},
+		{8, "0x10"},
+		{8, "100"},
+		{10, "400"},
+		{3, "8"},
+	}
+	for i, test := range tests {
+		if _, err := ParseHex(test.size, test.s); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestParseAny(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"0xFF", "11111111"},
+		{"0x0f", "00001111"},
+		{"0X1 00", "000100000000"},
+		{"0b1010", "1010"},
+		{"0B 10 10", "1010"},
+		{"0o17", "001111"},
+		{"0O7", "111"},
+		{"1010", "1010"},
+		{"0", "0"},
+		{"01", "01"},
+	}
+	for i, test := range tests {
+		ba, err := ParseAny(test.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestParseAnyError(t *testing.T) {
+	tests := []string{"", " ", "0x", "0b", "0o ", "0b102", "0o8", "0xg", "12", "0z1"}
+	for i, test := range tests {
+		if _, err := ParseAny(test); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestToHex(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"10100101", "a5"},
+		{"1111111111", "3ff"},
+		{"0000000101", "005"},
+		{"111", "7"},
+		{"1", "1"},
+		{"1101010111100", "1abc"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got := ba.ToHex()
+		if got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		ba2, err := ParseHex(ba.Size(), got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ba2.Equal(ba) {
+			t.Errorf("%d: got %q, want %q", i, ba2, ba)
+		}
+	}
+}
+
 func TestString(t *testing.T) {
 	tests := []string{
 		"0000000000",
@@ -118,6 +380,55 @@ func TestClear(t *testing.T) {
 	}
 }
 
+func TestRandomFill(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, p := range []float64{0.5, 0.1, 0.9} {
+		ba := New(100003)
+		ba.RandomFill(r, p)
+		got := float64(ba.Count()) / float64(ba.Size())
+		if math.Abs(got-p) > 0.01 {
+			t.Errorf("%g: got ratio %g", p, got)
+		}
+		if pad := ba.data[len(ba.data)-1] &^ ba.lastMask(); pad != 0 {
+			t.Errorf("%g: padding bits set: %08b", p, pad)
+		}
+	}
+	ba := New(1000)
+	ba.RandomFill(nil, 0.5)
+	if ba.AllZero() || ba.AllOne() {
+		t.Errorf("got %q", ba)
+	}
+	ba.RandomFill(nil, 0)
+	if !ba.AllZero() {
+		t.Errorf("got %q, want all zeros", ba)
+	}
+	ba.RandomFill(nil, 1)
+	if !ba.AllOne() {
+		t.Errorf("got %q, want all ones", ba)
+	}
+}
+
+func TestRandomFillReproducible(t *testing.T) {
+	for _, p := range []float64{0.5, 0.3} {
+		ba1, ba2 := New(1000), New(1000)
+		ba1.RandomFill(rand.New(rand.NewSource(42)), p)
+		ba2.RandomFill(rand.New(rand.NewSource(42)), p)
+		if !ba1.Equal(ba2) {
+			t.Errorf("%g: got different bit arrays", p)
+		}
+	}
+}
+
+func TestRandomFillPanic(t *testing.T) {
+	for i, p := range []float64{-0.1, 1.1, math.NaN()} {
+		func() {
+			defer func() { recover() }()
+			New(10).RandomFill(nil, p)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
 func TestSetAll(t *testing.T) {
 	tests := []struct {
 		size int
@@ -137,6 +448,22 @@ func TestSetAll(t *testing.T) {
 	}
 }
 
+func TestFill(t *testing.T) {
+	for _, size := range []int{1, 4, 8, 10, 16} {
+		ba1, ba2 := MustParse(strings.Repeat("01", size)[:size]), New(size)
+		ba1.Fill(true)
+		ba2.SetAll()
+		if !ba1.Equal(ba2) {
+			t.Errorf("%d: got %q, want %q", size, ba1, ba2)
+		}
+		ba1.Fill(false)
+		ba2.Clear()
+		if !ba1.Equal(ba2) {
+			t.Errorf("%d: got %q, want %q", size, ba1, ba2)
+		}
+	}
+}
+
 func TestSet(t *testing.T) {
 	want := "0100000010"
 	ba := New(10, 1)
@@ -366,6 +693,161 @@ func TestAndNotDiffSize(t *testing.T) {
 	t.Error("did not panic")
 }
 
+func TestNand(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   string
+	}{
+		{"0000", "1111", "1111"},
+		{"1111", "0000", "1111"},
+		{"0101", "0100", "1011"},
+		{"00000000", "11111111", "11111111"},
+		{"11111111", "00000000", "11111111"},
+		{"01010101", "01000110", "10111011"},
+		{"0000000000", "1111111111", "1111111111"},
+		{"1111111111", "0000000000", "1111111111"},
+		{"0101010101", "0100000110", "1011111011"},
+		{"0000000000000000", "1111111111111111", "1111111111111111"},
+		{"1111111111111111", "0000000000000000", "1111111111111111"},
+		{"0101010101010101", "0100000000000110", "1011111111111011"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s1)
+		ba.Nand(MustParse(test.s2))
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got, want := ba.Count(), strings.Count(test.want, "1"); got != want {
+			t.Errorf("%d: got count %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestNandDiffSize(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Nand(New(5))
+	t.Error("did not panic")
+}
+
+func TestNor(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   string
+	}{
+		{"0000", "1111", "0000"},
+		{"1111", "0000", "0000"},
+		{"0101", "0100", "1010"},
+		{"00000000", "11111111", "00000000"},
+		{"11111111", "00000000", "00000000"},
+		{"01010101", "01000110", "10101000"},
+		{"0000000000", "1111111111", "0000000000"},
+		{"1111111111", "0000000000", "0000000000"},
+		{"0101010101", "0100000110", "1010101000"},
+		{"0000000000000000", "1111111111111111", "0000000000000000"},
+		{"1111111111111111", "0000000000000000", "0000000000000000"},
+		{"0101010101010101", "0100000000000110", "1010101010101000"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s1)
+		ba.Nor(MustParse(test.s2))
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got, want := ba.Count(), strings.Count(test.want, "1"); got != want {
+			t.Errorf("%d: got count %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestNorDiffSize(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Nor(New(5))
+	t.Error("did not panic")
+}
+
+func TestXnor(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   string
+	}{
+		{"0000", "1111", "0000"},
+		{"1111", "0000", "0000"},
+		{"0101", "0100", "1110"},
+		{"00000000", "11111111", "00000000"},
+		{"11111111", "00000000", "00000000"},
+		{"01010101", "01000110", "11101100"},
+		{"0000000000", "1111111111", "0000000000"},
+		{"1111111111", "0000000000", "0000000000"},
+		{"0101010101", "0100000110", "1110101100"},
+		{"0000000000000000", "1111111111111111", "0000000000000000"},
+		{"1111111111111111", "0000000000000000", "0000000000000000"},
+		{"0101010101010101", "0100000000000110", "1110101010101100"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s1)
+		ba.Xnor(MustParse(test.s2))
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got, want := ba.Count(), strings.Count(test.want, "1"); got != want {
+			t.Errorf("%d: got count %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestXnorDiffSize(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Xnor(New(5))
+	t.Error("did not panic")
+}
+
+func TestApply(t *testing.T) {
+	tests := []string{"0", "1", "0101", "1100000101", "0101010101010101"}
+	for i, test := range tests {
+		got, want := MustParse(test), MustParse(test)
+		got.Apply(func(_ int, v bool) bool { return !v })
+		want.Not()
+		if !got.Equal(want) {
+			t.Errorf("%d: got %q, want %q", i, got, want)
+		}
+	}
+	ba := MustParse("0000000101")
+	var idx []int
+	ba.Apply(func(i int, v bool) bool {
+		idx = append(idx, i)
+		return i > 0 && ba.Get(i-1) || v
+	})
+	if got, want := ba.String(), "1111111111"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !reflect.DeepEqual(idx, want) {
+		t.Errorf("got %v, want %v", idx, want)
+	}
+}
+
+func TestMap(t *testing.T) {
+	tests := []string{"0", "1", "0101", "1100000101", "0101010101010101"}
+	for i, test := range tests {
+		ba := MustParse(test)
+		if got := ba.Map(func(_ int, v bool) bool { return v }); !got.Equal(ba) || got == ba {
+			t.Errorf("%d: got %q, want %q", i, got, ba)
+		}
+		got := ba.Map(func(_ int, v bool) bool { return !v })
+		want := MustParse(test)
+		want.Not()
+		if !got.Equal(want) {
+			t.Errorf("%d: got %q, want %q", i, got, want)
+		}
+		if ba.String() != test {
+			t.Errorf("%d: bit array modified: got %q", i, ba)
+		}
+	}
+	ba := MustParse("1100000101")
+	if got, want := ba.Map(func(i int, v bool) bool { return i%2 == 0 && v }).String(), "0100000101"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func TestNot(t *testing.T) {
 	tests := []struct {
 		s    string
@@ -429,17 +911,61 @@ func TestRotate(t *testing.T) {
 	}
 }
 
-func TestShift(t *testing.T) {
+func TestRotateRange(t *testing.T) {
 	tests := []struct {
-		s    string
-		n    int
-		want string
+		s          string
+		start, end int
+		n          int
+		want       string
 	}{
-		{"0001", 0, "0001"},
-		{"0001", 1, "0010"},
-		{"0001", 3, "1000"},
-		{"0001", 4, "0000"},
-		{"0001", 5, "0000"},
+		{"1010 1010 0011 0101", 4, 8, 0, "1010 1010 0011 0101"},
+		{"1010 1010 0011 0101", 4, 8, 1, "1010 1010 0110 0101"},
+		{"1010 1010 0011 0101", 4, 8, -1, "1010 1010 1001 0101"},
+		{"1010 1010 0011 0101", 4, 8, 4, "1010 1010 0011 0101"},
+		{"1010 1010 0011 0101", 4, 8, 5, "1010 1010 0110 0101"},
+		{"1010 1010 0011 0101", 4, 8, -6, "1010 1010 1100 0101"},
+		{"1010 1010 0011 0101", 6, 10, 1, "1010 1000 0111 0101"},
+		{"1010 1010 0011 0101", 0, 16, 1, "0101 0100 0110 1011"},
+		{"1010 1010 0011 0101", 5, 5, 1, "1010 1010 0011 0101"},
+		{"1010 1010 0011 0101", 16, 16, 1, "1010 1010 0011 0101"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		ba.RotateRange(test.start, test.end, test.n)
+		if got, want := ba.String(), strings.ReplaceAll(test.want, " ", ""); got != want {
+			t.Errorf("%d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestRotateRangePanic(t *testing.T) {
+	tests := []struct {
+		start, end int
+	}{
+		{-1, 4},
+		{5, 4},
+		{0, 17},
+	}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			New(16).RotateRange(test.start, test.end, 1)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestShift(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"0001", 0, "0001"},
+		{"0001", 1, "0010"},
+		{"0001", 3, "1000"},
+		{"0001", 4, "0000"},
+		{"0001", 5, "0000"},
 		{"1000", -1, "0100"},
 		{"1000", -4, "0000"},
 		{"00000001", 7, "10000000"},
@@ -465,137 +991,2836 @@ func TestShift(t *testing.T) {
 	}
 }
 
-func TestEqual(t *testing.T) {
+func TestArithmeticShiftRight(t *testing.T) {
 	tests := []struct {
-		s1, s2 string
+		s     string
+		n     int
+		want  string
+		shift string
 	}{
-		{"0101", "010"},
-		{"0101", "1010"},
-		{"01010101", "10101010"},
-		{"0101010101", "1010101010"},
+		{"1100000101", 0, "1100000101", "1100000101"},
+		{"1100000101", -3, "1100000101", "1100000101"},
+		{"1100000101", 1, "1110000010", "0110000010"},
+		{"1100000101", 3, "1111100000", "0001100000"},
+		{"1100000101", 10, "1111111111", "0000000000"},
+		{"1100000101", 11, "1111111111", "0000000000"},
+		{"0100000101", 2, "0001000001", "0001000001"},
+		{"1000000000000000", 8, "1111111110000000", "0000000010000000"},
+		{"1", 1, "1", "0"},
 	}
-	for _, test := range tests {
-		ba1a := MustParse(test.s1)
-		ba1b := MustParse(test.s1)
-		ba2a := MustParse(test.s2)
-		ba2b := MustParse(test.s2)
-		if !ba1a.Equal(ba1b) {
-			t.Errorf("%v = %v: got false, want true", ba1a, ba1b)
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		ba.ArithmeticShiftRight(test.n)
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
 		}
-		if !ba2a.Equal(ba2b) {
-			t.Errorf("%v = %v: got false, want true", ba2a, ba2b)
+		if test.n > 0 {
+			ba = MustParse(test.s)
+			ba.Shift(-test.n)
+			if got := ba.String(); got != test.shift {
+				t.Errorf("%d: got %q, want %q", i, got, test.shift)
+			}
 		}
-		if ba1a.Equal(ba2a) {
-			t.Errorf("%v = %v: got true, want false", ba1a, ba2a)
+	}
+}
+
+func TestShiftBoundary(t *testing.T) {
+	tests := []string{"1111111111", "1011001110", "1111111111111", "1000110101011"}
+	for i, test := range tests {
+		size := len(test)
+		for n := -size - 1; n <= size+1; n++ {
+			var want string
+			switch {
+			case n >= size || -n >= size:
+				want = strings.Repeat("0", size)
+			case n >= 0:
+				want = test[n:] + strings.Repeat("0", n)
+			default:
+				want = strings.Repeat("0", -n) + test[:size+n]
+			}
+			ba := MustParse(test)
+			ba.Shift(n)
+			if got := ba.String(); got != want {
+				t.Errorf("%d/%d: got %q, want %q", i, n, got, want)
+			}
+			if pad := ba.data[len(ba.data)-1] &^ ba.lastMask(); pad != 0 {
+				t.Errorf("%d/%d: padding bits set: %08b", i, n, pad)
+			}
 		}
-		if ba2a.Equal(ba1a) {
-			t.Errorf("%v = %v: got true, want false", ba2a, ba1a)
+		for _, n := range []int{1, -1} {
+			ba := MustParse(test)
+			ba.Shift(n * (size - 1))
+			ba.Shift(n)
+			if !ba.AllZero() {
+				t.Errorf("%d: got %q, want all zeros", i, ba)
+			}
 		}
 	}
 }
 
-func TestCount(t *testing.T) {
+func TestShiftBytes(t *testing.T) {
+	for _, size := range []int{8, 10, 16, 21, 64, 100} {
+		src := New(size)
+		for i := 0; i < size; i += 3 {
+			src.set(i)
+		}
+		for k := -(size - 1) / bitsN; k <= (size-1)/bitsN; k++ {
+			n := k * bitsN
+			got, want := Clone(src), Clone(src)
+			got.Shift(n)
+			want.shiftBits(n)
+			if !got.Equal(want) || !reflect.DeepEqual(got.data, want.data) {
+				t.Errorf("%d/%d: got %q, want %q", size, n, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkShiftBytes(b *testing.B) {
+	ba := benchmarkArray()
+	for i := 0; i < b.N; i++ {
+		ba.Shift(800)
+	}
+}
+
+func BenchmarkShiftBits(b *testing.B) {
+	ba := benchmarkArray()
+	for i := 0; i < b.N; i++ {
+		ba.shiftBits(800)
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	tests := []struct {
+		dst, src         string
+		dstStart         int
+		srcStart, srcEnd int
+		want             string
+	}{
+		{"0000000000000000", "11010110", 8, 0, 8, "1101011000000000"},
+		{"0000000000000000", "11010110", 0, 0, 8, "0000000011010110"},
+		{"1111111111111111", "11010110", 3, 1, 6, "1111111101011111"},
+		{"0000000000", "1111", 6, 0, 4, "1111000000"},
+		{"0000000000", "1111", 6, 2, 2, "0000000000"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.dst)
+		ba.CopyFrom(test.dstStart, MustParse(test.src), test.srcStart, test.srcEnd)
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestCopyFromSelf(t *testing.T) {
+	tests := []struct {
+		s                string
+		dstStart         int
+		srcStart, srcEnd int
+		want             string
+	}{
+		{"0000001111", 2, 0, 4, "0000111111"},
+		{"1111000000", 4, 6, 10, "1111110000"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		ba.CopyFrom(test.dstStart, ba, test.srcStart, test.srcEnd)
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestCopyFromPanic(t *testing.T) {
+	tests := []struct {
+		dstStart, srcStart, srcEnd int
+	}{
+		{7, 0, 4},
+		{-1, 0, 4},
+		{0, 2, 1},
+		{0, 0, 5},
+	}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			New(10).CopyFrom(test.dstStart, New(4), test.srcStart, test.srcEnd)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestGrow(t *testing.T) {
+	s := "1100000101"
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, s},
+		{1, "0" + s},
+		{6, "000000" + s},
+		{40, strings.Repeat("0", 40) + s},
+	}
+	for i, test := range tests {
+		ba := MustParse(s)
+		ba.Grow(test.n)
+		if got := ba.Size(); got != len(test.want) {
+			t.Errorf("%d: got size %d, want %d", i, got, len(test.want))
+		}
+		if got := ba.Count(); got != 4 {
+			t.Errorf("%d: got count %d, want 4", i, got)
+		}
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		ba.Set(ba.Size() - 1)
+		if got, want := ba.String(), "1"+test.want[1:]; got != want {
+			t.Errorf("%d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestGrowPanic(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Grow(-1)
+	t.Error("did not panic")
+}
+
+func TestAppend(t *testing.T) {
 	tests := []struct {
 		s    string
-		want int
+		bits []bool
+		want string
 	}{
-		{"0000", 0},
-		{"1111", 4},
-		{"0101", 2},
-		{"00000000", 0},
-		{"11111111", 8},
-		{"01010101", 4},
-		{"0000000000", 0},
-		{"1111111111", 10},
-		{"0101010101", 5},
-		{"0000000000000000", 0},
-		{"1111111111111111", 16},
-		{"0101010101010101", 8},
+		{"0", []bool{true, true, false, true}, "10110"},
+		{"1", []bool{}, "1"},
+		{"1111111", []bool{false, true}, "101111111"},
+		{"11111111", []bool{true, false, false, false, false, false, false, false, true}, "10000000111111111"},
 	}
 	for i, test := range tests {
-		if got := MustParse(test.s).Count(); got != test.want {
-			t.Errorf("%d: got %d, want %d", i, got, test.want)
+		ba := MustParse(test.s)
+		ba.Append(test.bits...)
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got := ba.Size(); got != len(test.want) {
+			t.Errorf("%d: got size %d, want %d", i, got, len(test.want))
 		}
 	}
 }
 
-func TestSize(t *testing.T) {
-	want := 4
-	tests := []*BitArray{New(4), MustParse("1010")}
+func TestInsertDeleteBit(t *testing.T) {
+	tests := []struct {
+		s    string
+		idx  int
+		v    bool
+		want string
+	}{
+		{"1111", 0, false, "11110"},
+		{"1111", 4, false, "01111"},
+		{"0000", 2, true, "00100"},
+		{"11111111", 3, false, "111110111"},
+		{"1100000101", 9, false, "10100000101"},
+		{"1100000101", 10, true, "11100000101"},
+	}
 	for i, test := range tests {
-		if got := test.Size(); got != want {
-			t.Errorf("%d: got %d, want %d", i, got, want)
+		ba := MustParse(test.s)
+		ba.InsertBit(test.idx, test.v)
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		ba.DeleteBit(test.idx)
+		if got := ba.String(); got != test.s {
+			t.Errorf("%d: got %q, want %q", i, got, test.s)
 		}
 	}
 }
 
-func TestLeadingTrailingZeros(t *testing.T) {
+func TestDeleteBit(t *testing.T) {
 	tests := []struct {
-		s              string
-		want_l, want_t int
+		s    string
+		idx  int
+		want string
 	}{
-		{"1001", 0, 0},
-		{"0110", 1, 1},
-		{"0000", 4, 4},
-		{"10000001", 0, 0},
-		{"01000010", 1, 1},
-		{"00000000", 8, 8},
-		{"1000000001", 0, 0},
-		{"0100000010", 1, 1},
-		{"0000000000", 10, 10},
-		{"1000000000000001", 0, 0},
-		{"0100000000000010", 1, 1},
-		{"0000000000000000", 16, 16},
+		{"1110", 0, "111"},
+		{"0111", 3, "111"},
+		{"101010101", 4, "10100101"},
 	}
 	for i, test := range tests {
 		ba := MustParse(test.s)
-		got_l := ba.LeadingZeros()
-		got_t := ba.TrailingZeros()
-		if got_l != test.want_l || got_t != test.want_t {
-			t.Errorf("%d: got %d and %d, want %d and %d", i, got_l, test.want_l, got_t, test.want_t)
+		ba.DeleteBit(test.idx)
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
 		}
 	}
 }
 
-func TestConcat(t *testing.T) {
+func TestInsertBitIdx(t *testing.T) {
+	defer func() { recover() }()
+	New(4).InsertBit(5, true)
+	t.Error("did not panic")
+}
+
+func TestDeleteBitPanic(t *testing.T) {
 	tests := []struct {
-		s1, s2 string
+		size, idx int
 	}{
-		{"0101", "101"},
-		{"0101", "1010"},
-		{"0101", "10101"},
-		{"01010101", "1010"},
-		{"01010101", "10101010"},
-		{"0101010101", "1010"},
-		{"0101010101", "101010"},
-		{"0101010101", "10101010"},
+		{4, 4},
+		{4, -1},
+		{1, 0},
 	}
 	for i, test := range tests {
-		ba := Concat(MustParse(test.s1), MustParse(test.s2))
-		want := test.s1 + test.s2
-		if got := ba.String(); got != want {
+		func() {
+			defer func() { recover() }()
+			New(test.size).DeleteBit(test.idx)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s       string
+		newSize int
+		want    string
+	}{
+		{"1111111111", 4, "1111"},
+		{"1111111111", 8, "11111111"},
+		{"1111111111", 9, "111111111"},
+		{"1111111111", 10, "1111111111"},
+		{"0101010101010101", 3, "101"},
+		{"0101010101010101", 1, "1"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		ba.Truncate(test.newSize)
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got, want := ba.Count(), strings.Count(test.want, "1"); got != want {
+			t.Errorf("%d: got count %d, want %d", i, got, want)
+		}
+		ba.Grow(ba.Size())
+		if got, want := ba.String(), strings.Repeat("0", len(test.want))+test.want; got != want {
 			t.Errorf("%d: got %q, want %q", i, got, want)
 		}
 	}
 }
 
-func TestMarshalUnmarshal(t *testing.T) {
-	tests := []string{
-		"0101", "01010101", "0101010101", "0101010101010101",
+func TestTruncatePanic(t *testing.T) {
+	tests := []int{0, 5}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			New(4).Truncate(test)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		s       string
+		newSize int
+		want    string
+	}{
+		{"1100000101", 10, "1100000101"},
+		{"1100000101", 12, "001100000101"},
+		{"1100000101", 24, "000000000000001100000101"},
+		{"1100000101", 9, "100000101"},
+		{"1100000101", 3, "101"},
 	}
 	for i, test := range tests {
-		ba1 := MustParse(test)
-		buf, err := ba1.MarshalBinary()
-		if err != nil {
-			t.Fatal(err)
+		ba := MustParse(test.s)
+		ba.Resize(test.newSize)
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
 		}
-		ba2 := new(BitArray)
-		err = ba2.UnmarshalBinary(buf)
-		if err != nil {
-			t.Fatal(err)
+	}
+}
+
+func TestResizeUpDown(t *testing.T) {
+	s := "1100000101"
+	ba := MustParse(s)
+	ba.Resize(30)
+	ba.Resize(10)
+	if got := ba.String(); got != s {
+		t.Errorf("got %q, want %q", got, s)
+	}
+	ba.Resize(4)
+	ba.Resize(10)
+	if got, want := ba.String(), "0000000101"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestResizePanic(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Resize(0)
+	t.Error("did not panic")
+}
+
+func TestCombineExtend(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		op     func(ba, other *BitArray)
+		want   string
+	}{
+		{"1011", "1100000111", (*BitArray).OrExtend, "1100001111"},
+		{"1100000111", "1011", (*BitArray).OrExtend, "1100001111"},
+		{"1011", "1100000111", (*BitArray).AndExtend, "0000000011"},
+		{"1100000111", "1011", (*BitArray).AndExtend, "0000000011"},
+		{"1011", "1100000111", (*BitArray).XorExtend, "1100001100"},
+		{"1100000111", "1011", (*BitArray).XorExtend, "1100001100"},
+		{"1011", "0110", (*BitArray).XorExtend, "1101"},
+	}
+	for i, test := range tests {
+		ba, other := MustParse(test.s1), MustParse(test.s2)
+		test.op(ba, other)
+		if want := MustParse(test.want); !ba.Equal(want) {
+			t.Errorf("%d: got %q, want %q", i, ba, test.want)
 		}
-		if got := ba2.String(); got != test {
-			t.Errorf("%d: got %q, want %q", i, got, test)
+		if got := other.String(); got != test.s2 {
+			t.Errorf("%d: other modified: got %q, want %q", i, got, test.s2)
+		}
+	}
+}
+
+func TestDifferentiateIntegrate(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"1111", "0001"},
+		{"0000", "0000"},
+		{"1111111111", "0000000001"},
+		{"0011110000", "0100010000"},
+		{"0101010101", "1111111111"},
+		{"1100000101", "0100001111"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		d := ba.Differentiate()
+		if got := d.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got := d.Integrate().String(); got != test.s {
+			t.Errorf("%d: got %q, want %q", i, got, test.s)
+		}
+		if got := ba.Integrate().Differentiate().String(); got != test.s {
+			t.Errorf("%d: got %q, want %q", i, got, test.s)
+		}
+	}
+}
+
+func TestGray(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"0000", "0000"},
+		{"0001", "0001"},
+		{"0010", "0011"},
+		{"0011", "0010"},
+		{"0111", "0100"},
+		{"1000", "1100"},
+		{"1111", "1000"},
+		{"1100000101", "1010000111"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got := ba.ToGray()
+		if got.String() != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if back := got.FromGray(); !back.Equal(ba) {
+			t.Errorf("%d: got %q, want %q", i, back, ba)
+		}
+	}
+}
+
+func TestGrayConsecutive(t *testing.T) {
+	const size = 10
+	prev := FromUint64(size, 0).ToGray()
+	for v := uint64(1); v < 1<<size; v++ {
+		g := FromUint64(size, v).ToGray()
+		if d := g.HammingDistance(prev); d != 1 {
+			t.Errorf("%d: got distance %d, want 1", v, d)
+		}
+		if got, _ := g.FromGray().Uint64(); got != v {
+			t.Errorf("%d: got %d", v, got)
+		}
+		prev = g
+	}
+}
+
+func TestDilateErode(t *testing.T) {
+	tests := []struct {
+		s             string
+		radius        int
+		dilate, erode string
+	}{
+		{"0001000", 1, "0011100", "0000000"},
+		{"0001000", 0, "0001000", "0001000"},
+		{"0011100", 1, "0111110", "0001000"},
+		{"1000001", 2, "1110111", "0000000"},
+		{"1111111", 1, "1111111", "0111110"},
+		{"0111111111", 3, "1111111111", "0000111000"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		if got := ba.Dilate(test.radius).String(); got != test.dilate {
+			t.Errorf("%d: got %q, want %q", i, got, test.dilate)
+		}
+		if got := ba.Erode(test.radius).String(); got != test.erode {
+			t.Errorf("%d: got %q, want %q", i, got, test.erode)
+		}
+	}
+}
+
+func TestDilateErodeNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{1, 7, 64, 100} {
+		ba := New(size)
+		ba.RandomFill(r, 0.7)
+		for _, radius := range []int{0, 1, 2, 5, size - 1, size, size + 1, math.MaxInt} {
+			dilate, erode := New(size), New(size)
+			for i := 0; i < size; i++ {
+				anySet, allSet := false, true
+				for j := i - min(radius, size); j <= i+min(radius, size); j++ {
+					v := j >= 0 && j < size && ba.Get(j)
+					anySet = anySet || v
+					allSet = allSet && v
+				}
+				if anySet {
+					dilate.Set(i)
+				}
+				if allSet {
+					erode.Set(i)
+				}
+			}
+			if got := ba.Dilate(radius); !got.Equal(dilate) {
+				t.Errorf("%d/%d: got %q, want %q", size, radius, got, dilate)
+			}
+			if got := ba.Erode(radius); !got.Equal(erode) {
+				t.Errorf("%d/%d: got %q, want %q", size, radius, got, erode)
+			}
+		}
+	}
+}
+
+func BenchmarkDilate(b *testing.B) {
+	ba := benchmarkArray()
+	for i := 0; i < b.N; i++ {
+		ba.Dilate(2)
+	}
+}
+
+func TestDilateErodePanic(t *testing.T) {
+	funcs := []func(ba *BitArray, radius int) *BitArray{(*BitArray).Dilate, (*BitArray).Erode}
+	for i, f := range funcs {
+		func() {
+			defer func() { recover() }()
+			f(New(4), -1)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+	}{
+		{"0101", "010"},
+		{"0101", "1010"},
+		{"01010101", "10101010"},
+		{"0101010101", "1010101010"},
+	}
+	for _, test := range tests {
+		ba1a := MustParse(test.s1)
+		ba1b := MustParse(test.s1)
+		ba2a := MustParse(test.s2)
+		ba2b := MustParse(test.s2)
+		if !ba1a.Equal(ba1b) {
+			t.Errorf("%v = %v: got false, want true", ba1a, ba1b)
+		}
+		if !ba2a.Equal(ba2b) {
+			t.Errorf("%v = %v: got false, want true", ba2a, ba2b)
+		}
+		if ba1a.Equal(ba2a) {
+			t.Errorf("%v = %v: got true, want false", ba1a, ba2a)
+		}
+		if ba2a.Equal(ba1a) {
+			t.Errorf("%v = %v: got true, want false", ba2a, ba1a)
+		}
+	}
+}
+
+func TestEqualValue(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   bool
+	}{
+		{"0101", "00000101", true},
+		{"0101", "0101", true},
+		{"0000", "0000000000000000", true},
+		{"0", "0000000000", true},
+		{"0101", "10000101", false},
+		{"0101", "1000000000000101", false},
+		{"1100000101", "00000001100000101", true},
+		{"1100000101", "0100000101", false},
+	}
+	for i, test := range tests {
+		ba1, ba2 := MustParse(test.s1), MustParse(test.s2)
+		if got := ba1.EqualValue(ba2); got != test.want {
+			t.Errorf("%d: got %t, want %t", i, got, test.want)
+		}
+		if got := ba2.EqualValue(ba1); got != test.want {
+			t.Errorf("%d: got %t, want %t", i, got, test.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"1000", "0111", 1},
+		{"0111", "1000", -1},
+		{"0101", "00000101", 0},
+		{"0000", "0000000000", 0},
+		{"0", "1", -1},
+		{"1", "0000000000", 1},
+		{"1100000101", "1100000110", -1},
+		{"10000000000000000", "1111111111111111", 1},
+		{"0000000000000000001", "11", -1},
+	}
+	for i, test := range tests {
+		ba1, ba2 := MustParse(test.s1), MustParse(test.s2)
+		if got := ba1.Compare(ba2); got != test.want {
+			t.Errorf("%d: got %d, want %d", i, got, test.want)
+		}
+		if got := ba2.Compare(ba1); got != -test.want {
+			t.Errorf("%d: got %d, want %d", i, got, -test.want)
+		}
+	}
+}
+
+func TestCompareFunc(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"1000", "0111", 1},
+		{"0101", "00000101", -1},
+		{"0000", "0000000000", -1},
+		{"0101", "0101", 0},
+		{"1", "0000000000", 1},
+		{"0000000000000000001", "11", -1},
+	}
+	for i, test := range tests {
+		ba1, ba2 := MustParse(test.s1), MustParse(test.s2)
+		if got := Compare(ba1, ba2); got != test.want {
+			t.Errorf("%d: got %d, want %d", i, got, test.want)
+		}
+		if got := Compare(ba2, ba1); got != -test.want {
+			t.Errorf("%d: got %d, want %d", i, got, -test.want)
+		}
+	}
+}
+
+func TestCompareFuncSort(t *testing.T) {
+	list := []*BitArray{
+		MustParse("0011"), MustParse("1"), MustParse("00000011"), MustParse("0000"),
+		MustParse("10"), MustParse("0"), MustParse("011"), MustParse("100000000"),
+	}
+	slices.SortFunc(list, Compare)
+	want := []string{"0", "0000", "1", "10", "011", "0011", "00000011", "100000000"}
+	for i, ba := range list {
+		if got := ba.String(); got != want[i] {
+			t.Errorf("%d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+// setPadding sets the unused bits of the last byte.
+func setPadding(ba *BitArray, pad uint8) {
+	ba.data[len(ba.data)-1] |= pad &^ ba.lastMask()
+}
+
+func TestEqualPadding(t *testing.T) {
+	tests := []string{"0101", "1100000101", "0", "1111111"}
+	for _, test := range tests {
+		ba1, ba2 := MustParse(test), MustParse(test)
+		setPadding(ba1, 0xff)
+		setPadding(ba2, 0x80)
+		if !ba1.Equal(ba2) || !ba2.Equal(ba1) {
+			t.Errorf("%v = %v: got false, want true", ba1, ba2)
+		}
+	}
+	ba1, ba2 := MustParse("1100000101"), MustParse("1100000100")
+	setPadding(ba1, 0xff)
+	if ba1.Equal(ba2) {
+		t.Errorf("%v = %v: got true, want false", ba1, ba2)
+	}
+}
+
+func TestPaddingIgnored(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+	}{
+		{"0101", "0101"},
+		{"1100000101", "1100000101"},
+		{"1100000101", "0000000101"},
+		{"0000000000", "1111111111"},
+		{"0101", "1010"},
+		{"1", "0"},
+	}
+	for i, test := range tests {
+		a1, a2 := MustParse(test.s1), MustParse(test.s2)
+		b1, b2 := MustParse(test.s1), MustParse(test.s2)
+		setPadding(b1, 0xff)
+		setPadding(b2, 0xa0)
+		if a1.Equal(a2) != b1.Equal(b2) {
+			t.Errorf("%d: Equal differs", i)
+		}
+		if (a1.Key() == a2.Key()) != (b1.Key() == b2.Key()) || a1.Key() != b1.Key() {
+			t.Errorf("%d: Key differs", i)
+		}
+		if a1.EqualValue(a2) != b1.EqualValue(b2) {
+			t.Errorf("%d: EqualValue differs", i)
+		}
+		if a1.Compare(a2) != b1.Compare(b2) || b1.Compare(a1) != 0 {
+			t.Errorf("%d: Compare differs", i)
+		}
+		if a1.HammingDistance(a2) != b1.HammingDistance(b2) {
+			t.Errorf("%d: HammingDistance differs", i)
+		}
+		if a1.Contains(a2) != b1.Contains(b2) || a2.Contains(a1) != b2.Contains(b1) {
+			t.Errorf("%d: Contains differs", i)
+		}
+		if a1.Overlaps(a2) != b1.Overlaps(b2) {
+			t.Errorf("%d: Overlaps differs", i)
+		}
+		idx1, ok1 := a1.HighestSet()
+		idx2, ok2 := b1.HighestSet()
+		if idx1 != idx2 || ok1 != ok2 {
+			t.Errorf("%d: HighestSet differs", i)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []string{"0101", "1100000101", "0", "11111111", "0101010101010101"}
+	for i, test := range tests {
+		ba := MustParse(test)
+		setPadding(ba, 0xff)
+		ba.Normalize()
+		if got := ba.String(); got != test {
+			t.Errorf("%d: got %q, want %q", i, got, test)
+		}
+		if got, want := ba.Count(), strings.Count(test, "1"); got != want {
+			t.Errorf("%d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"0000", 0},
+		{"1111", 4},
+		{"0101", 2},
+		{"00000000", 0},
+		{"11111111", 8},
+		{"01010101", 4},
+		{"0000000000", 0},
+		{"1111111111", 10},
+		{"0101010101", 5},
+		{"0000000000000000", 0},
+		{"1111111111111111", 16},
+		{"0101010101010101", 8},
+	}
+	for i, test := range tests {
+		if got := MustParse(test.s).Count(); got != test.want {
+			t.Errorf("%d: got %d, want %d", i, got, test.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	tests := []string{
+		"0000", "1111", "0101", "1100000101", "0101010101010101", "10000000011111111",
+	}
+	for i, test := range tests {
+		ba := MustParse(test)
+		want := 0
+		for idx := 0; idx <= ba.Size(); idx++ {
+			if got := ba.Rank(idx); got != want {
+				t.Errorf("%d/%d: got %d, want %d", i, idx, got, want)
+			}
+			if idx < ba.Size() && ba.Get(idx) {
+				want++
+			}
+		}
+		if got := ba.Rank(ba.Size()); got != ba.Count() {
+			t.Errorf("%d: got %d, want %d", i, got, ba.Count())
+		}
+	}
+}
+
+func TestRankPanic(t *testing.T) {
+	tests := []int{-1, 5}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			New(4).Rank(test)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestCountRange(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end int
+		want       int
+	}{
+		{"1100000101", 0, 10, 4},
+		{"1100000101", 0, 0, 0},
+		{"1100000101", 10, 10, 0},
+		{"1100000101", 1, 9, 2},
+		{"1100000101", 8, 10, 2},
+		{"1111111111111111", 3, 13, 10},
+	}
+	for i, test := range tests {
+		if got := MustParse(test.s).CountRange(test.start, test.end); got != test.want {
+			t.Errorf("%d: got %d, want %d", i, got, test.want)
+		}
+	}
+	ba := MustParse("10110000011101010011")
+	if got := ba.CountRange(0, ba.Size()); got != ba.Count() {
+		t.Errorf("got %d, want %d", got, ba.Count())
+	}
+	sum := ba.CountRange(0, 3) + ba.CountRange(3, 11) + ba.CountRange(11, 16) + ba.CountRange(16, 20)
+	if sum != ba.Count() {
+		t.Errorf("got %d, want %d", sum, ba.Count())
+	}
+}
+
+func TestCountRangePanic(t *testing.T) {
+	tests := []struct {
+		start, end int
+	}{
+		{-1, 2},
+		{3, 2},
+		{0, 5},
+	}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			New(4).CountRange(test.start, test.end)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestSetBitsWithin(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end int
+		want       bool
+	}{
+		{"0011000000", 6, 8, true},
+		{"0011000000", 0, 10, true},
+		{"0011000000", 4, 6, false},
+		{"0011000000", 7, 8, false},
+		{"0011000000", 6, 7, false},
+		{"0000000000", 3, 3, true},
+		{"1000000001", 0, 9, false},
+	}
+	for i, test := range tests {
+		if got := MustParse(test.s).SetBitsWithin(test.start, test.end); got != test.want {
+			t.Errorf("%d: got %t, want %t", i, got, test.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		s    string
+		k    int
+		want int
+		ok   bool
+	}{
+		{"0100", 0, 2, true},
+		{"0100", 1, -1, false},
+		{"0000", 0, -1, false},
+		{"0100110101", 4, 8, true},
+		{"0100110101", 3, 5, true},
+		{"0100110101", -1, -1, false},
+		{"1000000000000001", 1, 15, true},
+		{"1111111111111111", 9, 9, true},
+	}
+	for i, test := range tests {
+		got, ok := MustParse(test.s).Select(test.k)
+		if got != test.want || ok != test.ok {
+			t.Errorf("%d: got %d and %t, want %d and %t", i, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestSelectRank(t *testing.T) {
+	ba := MustParse("10110000011101010011")
+	for k := 0; k < ba.Count(); k++ {
+		idx, ok := ba.Select(k)
+		if !ok || !ba.Get(idx) || ba.Rank(idx) != k {
+			t.Errorf("%d: got %d and %t", k, idx, ok)
+		}
+	}
+}
+
+func TestSetClearBits(t *testing.T) {
+	tests := []struct {
+		s          string
+		set, unset []int
+	}{
+		{"1111111101", []int{0, 2, 3, 4, 5, 6, 7, 8, 9}, []int{1}},
+		{"0000", []int{}, []int{0, 1, 2, 3}},
+		{"1111", []int{0, 1, 2, 3}, []int{}},
+		{"0100110101", []int{0, 2, 4, 5, 8}, []int{1, 3, 6, 7, 9}},
+		{"1000000000000001", []int{0, 15}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		if got := ba.SetBits(); !reflect.DeepEqual(got, test.set) {
+			t.Errorf("%d: got %v, want %v", i, got, test.set)
+		}
+		if got := ba.ClearBits(); !reflect.DeepEqual(got, test.unset) {
+			t.Errorf("%d: got %v, want %v", i, got, test.unset)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ba := New(1000)
+	for i := 0; i < ba.Size(); i += 3 {
+		ba.Set(i)
+	}
+	for _, k := range []int{0, 1, 10, 334, 500} {
+		for range 10 {
+			got := ba.Sample(r, k)
+			if want := min(k, ba.Count()); len(got) != want {
+				t.Fatalf("%d: got %d indexes, want %d", k, len(got), want)
+			}
+			seen := make(map[int]bool)
+			for _, idx := range got {
+				if !ba.Get(idx) {
+					t.Errorf("%d: bit at index %d not set", k, idx)
+				}
+				if seen[idx] {
+					t.Errorf("%d: duplicate index %d", k, idx)
+				}
+				seen[idx] = true
+			}
+		}
+	}
+	if got := New(10).Sample(nil, 3); len(got) != 0 {
+		t.Errorf("got %v, want []", got)
+	}
+	got := New(10, 1, 2).Sample(nil, math.MaxInt)
+	slices.Sort(got)
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSamplePanic(t *testing.T) {
+	defer func() { recover() }()
+	New(10).Sample(nil, -1)
+	t.Error("did not panic")
+}
+
+func TestRunLengths(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []int
+	}{
+		{"0000000000", []int{10}},
+		{"1111111111", []int{10}},
+		{"0101010101", []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		{"011000", []int{3, 2, 1}},
+		{"0011101", []int{1, 1, 3, 2}},
+		{"1100000000000001", []int{1, 13, 2}},
+		{"1", []int{1}},
+	}
+	for i, test := range tests {
+		if got := MustParse(test.s).RunLengths(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestAllZeroAllOne(t *testing.T) {
+	tests := []struct {
+		s         string
+		zero, one bool
+	}{
+		{"0000", true, false},
+		{"1111", false, true},
+		{"0100", false, false},
+		{"00000000", true, false},
+		{"11111111", false, true},
+		{"11101111", false, false},
+		{"0000000000", true, false},
+		{"1111111111", false, true},
+		{"0111111111", false, false},
+		{"1111111110", false, false},
+		{"1000000000", false, false},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		if got := ba.AllZero(); got != test.zero {
+			t.Errorf("%d: got %t, want %t", i, got, test.zero)
+		}
+		if got := ba.AllOne(); got != test.one {
+			t.Errorf("%d: got %t, want %t", i, got, test.one)
+		}
+	}
+	for _, size := range []int{8, 10} {
+		ba := New(size)
+		if ba.AllOne() || !ba.AllZero() {
+			t.Errorf("%d: got %t and %t for new bit array", size, ba.AllOne(), ba.AllZero())
+		}
+		ba.SetAll()
+		if !ba.AllOne() || ba.AllZero() {
+			t.Errorf("%d: got %t and %t after SetAll", size, ba.AllOne(), ba.AllZero())
+		}
+	}
+}
+
+func TestAnyNone(t *testing.T) {
+	tests := []struct {
+		s   string
+		any bool
+	}{
+		{"0000", false},
+		{"0000000000", false},
+		{"1000000000", true},
+		{"0000000001", true},
+		{"1000000000000000", true},
+		{"1111", true},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		if got := ba.Any(); got != test.any {
+			t.Errorf("%d: got %t, want %t", i, got, test.any)
+		}
+		if got := ba.None(); got == test.any {
+			t.Errorf("%d: got %t, want %t", i, got, !test.any)
+		}
+	}
+}
+
+func TestAnyInRange(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end int
+		want       bool
+	}{
+		{"0000000000", 0, 10, false},
+		{"1000000000", 0, 10, true},
+		{"1000000000", 0, 9, false},
+		{"1000000000", 9, 10, true},
+		{"0000110000", 2, 4, false},
+		{"0000110000", 2, 5, true},
+		{"0000110000", 5, 5, false},
+	}
+	for i, test := range tests {
+		if got := MustParse(test.s).AnyInRange(test.start, test.end); got != test.want {
+			t.Errorf("%d: got %t, want %t", i, got, test.want)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []int
+	}{
+		{"0110011", []int{1, 1, 0, 0, 2, 2, 0}},
+		{"0000", []int{0, 0, 0, 0}},
+		{"1111", []int{1, 1, 1, 1}},
+		{"0101010101", []int{1, 0, 2, 0, 3, 0, 4, 0, 5, 0}},
+		{"1100000001", []int{1, 0, 0, 0, 0, 0, 0, 0, 2, 2}},
+	}
+	for i, test := range tests {
+		if got := MustParse(test.s).Labels(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"0000", "1111", 4},
+		{"0101", "0100", 1},
+		{"01010101", "01000110", 3},
+		{"0101010101", "0100000110", 4},
+		{"1100000000", "0100000000", 1},
+		{"0101010101010101", "0100000000000110", 7},
+	}
+	for i, test := range tests {
+		ba1, ba2 := MustParse(test.s1), MustParse(test.s2)
+		if got := ba1.HammingDistance(ba2); got != test.want {
+			t.Errorf("%d: got %d, want %d", i, got, test.want)
+		}
+		if got := ba1.HammingDistance(ba1); got != 0 {
+			t.Errorf("%d: got %d, want 0", i, got)
+		}
+		ba2 = Clone(ba1)
+		ba2.Not()
+		if got := ba1.HammingDistance(ba2); got != ba1.Size() {
+			t.Errorf("%d: got %d, want %d", i, got, ba1.Size())
+		}
+	}
+}
+
+func TestHammingDistanceDiffSize(t *testing.T) {
+	defer func() { recover() }()
+	New(4).HammingDistance(New(5))
+	t.Error("did not panic")
+}
+
+func TestParity(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"0000", false},
+		{"0001", true},
+		{"1001", false},
+		{"1101", true},
+		{"1100000101", false},
+		{"1100000111", true},
+		{"1111111111111111", false},
+		{"10000000000000000", true},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		if got := ba.Parity(); got != test.want {
+			t.Errorf("%d: got %t, want %t", i, got, test.want)
+		}
+		for _, even := range []bool{true, false} {
+			ba2 := Clone(ba)
+			ba2.AppendParityBit(even)
+			if ba2.Size() != ba.Size()+1 || !Slice(ba2, 0, ba.Size()).Equal(ba) {
+				t.Errorf("%d/%t: got %q", i, even, ba2)
+			}
+			if got := ba2.Get(ba.Size()); got != (test.want == even) {
+				t.Errorf("%d/%t: got parity bit %t", i, even, got)
+			}
+			if got := ba2.Parity(); got == even {
+				t.Errorf("%d/%t: got parity %t", i, even, got)
+			}
+		}
+	}
+}
+
+func TestOverlapsContains(t *testing.T) {
+	tests := []struct {
+		s1, s2             string
+		overlaps, contains bool
+	}{
+		{"0101", "1010", false, false},
+		{"0111", "1110", true, false},
+		{"0111", "0101", true, true},
+		{"0101", "0111", true, false},
+		{"0101", "0101", true, true},
+		{"0101", "0000", false, true},
+		{"0000", "0000", false, true},
+		{"1100000101", "0000000100", true, true},
+		{"1100000101", "0010000000", false, false},
+		{"1100000101", "1000000000", true, true},
+	}
+	for i, test := range tests {
+		ba1, ba2 := MustParse(test.s1), MustParse(test.s2)
+		if got := ba1.Overlaps(ba2); got != test.overlaps {
+			t.Errorf("%d: got %t, want %t", i, got, test.overlaps)
+		}
+		if got := ba1.Contains(ba2); got != test.contains {
+			t.Errorf("%d: got %t, want %t", i, got, test.contains)
+		}
+	}
+}
+
+func TestOverlapsContainsDiffSize(t *testing.T) {
+	funcs := []func(ba, other *BitArray) bool{(*BitArray).Overlaps, (*BitArray).Contains}
+	for i, f := range funcs {
+		func() {
+			defer func() { recover() }()
+			f(New(4), New(5))
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestIsSubsetOf(t *testing.T) {
+	tests := []struct {
+		s1, s2         string
+		subset, proper bool
+	}{
+		{"0101", "0111", true, true},
+		{"0111", "0101", false, false},
+		{"0101", "0101", true, false},
+		{"0101", "1010", false, false},
+		{"0000", "0000", true, false},
+		{"0000", "1010", true, true},
+		{"0000000000", "1100000101", true, true},
+		{"1000000001", "1100000101", true, true},
+		{"1100000101", "1100000101", true, false},
+	}
+	for i, test := range tests {
+		ba1, ba2 := MustParse(test.s1), MustParse(test.s2)
+		if got := ba1.IsSubsetOf(ba2); got != test.subset {
+			t.Errorf("%d: got %t, want %t", i, got, test.subset)
+		}
+		if got := ba1.IsProperSubsetOf(ba2); got != test.proper {
+			t.Errorf("%d: got %t, want %t", i, got, test.proper)
+		}
+	}
+}
+
+func TestIsSubsetOfDiffSize(t *testing.T) {
+	funcs := []func(ba, other *BitArray) bool{(*BitArray).IsSubsetOf, (*BitArray).IsProperSubsetOf}
+	for i, f := range funcs {
+		func() {
+			defer func() { recover() }()
+			f(New(4), New(5))
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   float64
+	}{
+		{"0000", "0000", 1},
+		{"0101", "0101", 1},
+		{"0101", "1010", 0},
+		{"0111", "1110", 0.5},
+		{"1100000001", "0100000011", 0.5},
+		{"0101010101", "0100000110", 1.0 / 3},
+		{"1111111111", "0000000001", 0.1},
+	}
+	for i, test := range tests {
+		if got := MustParse(test.s1).Jaccard(MustParse(test.s2)); got != test.want {
+			t.Errorf("%d: got %g, want %g", i, got, test.want)
+		}
+	}
+}
+
+func TestJaccardDiffSize(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Jaccard(New(5))
+	t.Error("did not panic")
+}
+
+func countBytewise(ba *BitArray) int {
+	cnt := 0
+	for _, x := range ba.data {
+		cnt += bits.OnesCount8(x)
+	}
+	return cnt
+}
+
+func TestCountLarge(t *testing.T) {
+	for _, size := range []int{63, 64, 65, 127, 200, 1001} {
+		ba := New(size)
+		for i := 0; i < size; i += 3 {
+			ba.Set(i)
+		}
+		if got, want := ba.Count(), countBytewise(ba); got != want {
+			t.Errorf("%d: got %d, want %d", size, got, want)
+		}
+		ba.SetAll()
+		if got := ba.Count(); got != size {
+			t.Errorf("%d: got %d, want %d", size, got, size)
+		}
+	}
+}
+
+func benchmarkArray() *BitArray {
+	ba := New(100000)
+	for i := 0; i < ba.Size(); i += 7 {
+		ba.Set(i)
+	}
+	return ba
+}
+
+func BenchmarkCount(b *testing.B) {
+	ba := benchmarkArray()
+	for i := 0; i < b.N; i++ {
+		ba.Count()
+	}
+}
+
+func BenchmarkCountBytewise(b *testing.B) {
+	ba := benchmarkArray()
+	for i := 0; i < b.N; i++ {
+		countBytewise(ba)
+	}
+}
+
+func TestSize(t *testing.T) {
+	want := 4
+	tests := []*BitArray{New(4), MustParse("1010")}
+	for i, test := range tests {
+		if got := test.Size(); got != want {
+			t.Errorf("%d: got %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestLeadingTrailingZeros(t *testing.T) {
+	tests := []struct {
+		s              string
+		want_l, want_t int
+	}{
+		{"1001", 0, 0},
+		{"0110", 1, 1},
+		{"0000", 4, 4},
+		{"10000001", 0, 0},
+		{"01000010", 1, 1},
+		{"00000000", 8, 8},
+		{"1000000001", 0, 0},
+		{"0100000010", 1, 1},
+		{"0000000000", 10, 10},
+		{"1000000000000001", 0, 0},
+		{"0100000000000010", 1, 1},
+		{"0000000000000000", 16, 16},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got_l := ba.LeadingZeros()
+		got_t := ba.TrailingZeros()
+		if got_l != test.want_l || got_t != test.want_t {
+			t.Errorf("%d: got %d and %d, want %d and %d", i, got_l, test.want_l, got_t, test.want_t)
+		}
+	}
+}
+
+func TestLeadingTrailingOnes(t *testing.T) {
+	tests := []struct {
+		s              string
+		want_l, want_t int
+	}{
+		{"0110", 0, 0},
+		{"1001", 1, 1},
+		{"1111", 4, 4},
+		{"1110", 3, 0},
+		{"01111111", 0, 7},
+		{"11111111", 8, 8},
+		{"1100000111", 2, 3},
+		{"0111111111", 0, 9},
+		{"1111111110", 9, 0},
+		{"1111111111", 10, 10},
+		{"1111111111100111", 11, 3},
+		{"1111111111111111", 16, 16},
+		{"1", 1, 1},
+		{"0", 0, 0},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got_l := ba.LeadingOnes()
+		got_t := ba.TrailingOnes()
+		if got_l != test.want_l || got_t != test.want_t {
+			t.Errorf("%d: got %d and %d, want %d and %d", i, got_l, got_t, test.want_l, test.want_t)
+		}
+	}
+}
+
+func TestSlice(t *testing.T) {
+	s := "1100000101"
+	tests := []struct {
+		start, end int
+		want       string
+	}{
+		{0, 10, "1100000101"},
+		{0, 3, "101"},
+		{7, 10, "110"},
+		{2, 9, "1000001"},
+		{5, 6, "0"},
+	}
+	for i, test := range tests {
+		if got := Slice(MustParse(s), test.start, test.end).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestSlicePanic(t *testing.T) {
+	tests := []struct {
+		start, end int
+		msg        string
+	}{
+		{0, 0, "empty slice"},
+		{3, 3, "empty slice"},
+		{10, 10, "empty slice"},
+		{4, 2, "slice bounds out of range"},
+		{-1, 4, "slice bounds out of range"},
+		{0, 11, "slice bounds out of range"},
+	}
+	for i, test := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != test.msg {
+					t.Errorf("%d: got %v, want %q", i, r, test.msg)
+				}
+			}()
+			Slice(New(10), test.start, test.end)
+		}()
+	}
+}
+
+func TestSub(t *testing.T) {
+	s := "1100000101"
+	tests := []struct {
+		start, end int
+		want       string
+	}{
+		{0, 10, "1100000101"},
+		{3, 9, "100000"},
+		{0, 1, "1"},
+		{2, -1, "1000001"},
+		{0, -9, "1"},
+		{9, 10, "1"},
+	}
+	for i, test := range tests {
+		if got := MustParse(s).Sub(test.start, test.end).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestSubPanic(t *testing.T) {
+	tests := []struct {
+		start, end int
+	}{
+		{3, 3},
+		{0, 0},
+		{4, 2},
+		{-1, 4},
+		{0, 11},
+		{0, -10},
+		{5, -5},
+	}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			New(10).Sub(test.start, test.end)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestConcat(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+	}{
+		{"0101", "101"},
+		{"0101", "1010"},
+		{"0101", "10101"},
+		{"01010101", "1010"},
+		{"01010101", "10101010"},
+		{"0101010101", "1010"},
+		{"0101010101", "101010"},
+		{"0101010101", "10101010"},
+	}
+	for i, test := range tests {
+		ba := Concat(MustParse(test.s1), MustParse(test.s2))
+		want := test.s1 + test.s2
+		if got := ba.String(); got != want {
+			t.Errorf("%d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestConcatAll(t *testing.T) {
+	tests := [][]string{
+		{"0101"},
+		{"0101", "101", "11"},
+		{"01010101", "1010", "1"},
+		{"0101", "10101010", "1100000101"},
+		{"01010101", "10101010", "11001100"},
+		{"0101", "101", "11", "1"},
+		{"0101010101", "1010", "01010101", "111"},
+		{"1", "1", "1", "1", "1", "1", "1", "1", "1"},
+	}
+	for i, test := range tests {
+		arrs := make([]*BitArray, len(test))
+		for j, s := range test {
+			arrs[j] = MustParse(s)
+		}
+		want := arrs[0]
+		for _, a := range arrs[1:] {
+			want = Concat(want, a)
+		}
+		got := ConcatAll(arrs...)
+		if !got.Equal(want) || got.String() != strings.Join(test, "") {
+			t.Errorf("%d: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestConcatAllPanic(t *testing.T) {
+	defer func() { recover() }()
+	ConcatAll()
+	t.Error("did not panic")
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		s         string
+		chunkSize int
+		want      []string
+	}{
+		{"0101010101", 4, []string{"0101", "0101", "01"}},
+		{"1100000101", 5, []string{"00101", "11000"}},
+		{"1100000101", 10, []string{"1100000101"}},
+		{"1100000101", 11, []string{"1100000101"}},
+		{"1100000101", 1, []string{"1", "0", "1", "0", "0", "0", "0", "0", "1", "1"}},
+		{"0101010111110000", 8, []string{"11110000", "01010101"}},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		chunks := ba.Split(test.chunkSize)
+		got := make([]string, len(chunks))
+		for j, c := range chunks {
+			got[j] = c.String()
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+		}
+		slices.Reverse(chunks)
+		if c := ConcatAll(chunks...); !c.Equal(ba) {
+			t.Errorf("%d: got %q, want %q", i, c, ba)
+		}
+	}
+}
+
+func TestSplitPanic(t *testing.T) {
+	defer func() { recover() }()
+	New(10).Split(0)
+	t.Error("did not panic")
+}
+
+func TestInterleave(t *testing.T) {
+	tests := []struct {
+		arrs []string
+		want string
+	}{
+		{[]string{"0000", "1111"}, "10101010"},
+		{[]string{"1100", "0101"}, "01110010"},
+		{[]string{"110", "011", "101"}, "101011110"},
+		{[]string{"1100000101"}, "1100000101"},
+		{[]string{"01", "10", "11", "00"}, "01100101"},
+	}
+	for i, test := range tests {
+		arrs := make([]*BitArray, len(test.arrs))
+		for j, s := range test.arrs {
+			arrs[j] = MustParse(s)
+		}
+		got := Interleave(arrs...)
+		if got.String() != test.want {
+			t.Errorf("%d: got %q, want %q", i, got.String(), test.want)
+		}
+		for j, a := range got.Deinterleave(len(arrs)) {
+			if !a.Equal(arrs[j]) {
+				t.Errorf("%d/%d: got %q, want %q", i, j, a, arrs[j])
+			}
+		}
+	}
+}
+
+func TestInterleavePanic(t *testing.T) {
+	tests := []func(){
+		func() { Interleave() },
+		func() { Interleave(New(4), New(5)) },
+		func() { New(10).Deinterleave(0) },
+		func() { New(10).Deinterleave(3) },
+	}
+	for i, f := range tests {
+		func() {
+			defer func() { recover() }()
+			f()
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestBinaryFuncs(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+	}{
+		{"0101", "0100"},
+		{"01010101", "01000110"},
+		{"0101010101", "0100000110"},
+		{"0101010101010101", "0100000000000110"},
+	}
+	funcs := []struct {
+		f      func(a, b *BitArray) *BitArray
+		method func(ba, other *BitArray)
+	}{
+		{And, (*BitArray).And},
+		{Or, (*BitArray).Or},
+		{Xor, (*BitArray).Xor},
+		{AndNot, (*BitArray).AndNot},
+		{Union, (*BitArray).Or},
+		{Intersection, (*BitArray).And},
+		{Difference, (*BitArray).AndNot},
+		{SymmetricDifference, (*BitArray).Xor},
+	}
+	for i, test := range tests {
+		for j, fs := range funcs {
+			a, b := MustParse(test.s1), MustParse(test.s2)
+			got := fs.f(a, b)
+			want := MustParse(test.s1)
+			fs.method(want, b)
+			if !got.Equal(want) {
+				t.Errorf("%d/%d: got %q, want %q", i, j, got, want)
+			}
+			if a.String() != test.s1 || b.String() != test.s2 {
+				t.Errorf("%d/%d: operands modified: got %q and %q", i, j, a, b)
+			}
+		}
+	}
+}
+
+func TestBinaryFuncsDiffSize(t *testing.T) {
+	funcs := []func(a, b *BitArray) *BitArray{And, Or, Xor, AndNot,
+		Union, Intersection, Difference, SymmetricDifference}
+	for i, f := range funcs {
+		func() {
+			defer func() { recover() }()
+			f(New(4), New(5))
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestPopCounts(t *testing.T) {
+	arrs := []*BitArray{MustParse("0000"), MustParse("1100000101"), MustParse("1111111111111111")}
+	if got, want := PopCounts(arrs), []int{0, 4, 16}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := PopCounts(nil); len(got) != 0 {
+		t.Errorf("got %v, want []", got)
+	}
+}
+
+func TestOrReduce(t *testing.T) {
+	a, b, c := MustParse("1000000001"), MustParse("0100000010"), MustParse("1100000100")
+	if got, want := OrReduce(a, b, c).String(), "1100000111"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := OrReduce(a).String(), "1000000001"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := a.String(); got != "1000000001" {
+		t.Errorf("operand modified: got %q", got)
+	}
+}
+
+func TestOrReducePanic(t *testing.T) {
+	tests := [][]*BitArray{{}, {New(4), New(4), New(5)}}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			OrReduce(test...)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestAndXorReduce(t *testing.T) {
+	tests := [][]string{
+		{"1100000101"},
+		{"1100000101", "0111111100"},
+		{"1100000101", "0111111100", "1110001111"},
+		{"1100000101", "0111111100", "1110001111", "0101010101"},
+	}
+	for i, test := range tests {
+		arrs := make([]*BitArray, len(test))
+		for j, s := range test {
+			arrs[j] = MustParse(s)
+		}
+		wantAnd, wantXor := Clone(arrs[0]), Clone(arrs[0])
+		for _, ba := range arrs[1:] {
+			wantAnd = And(wantAnd, ba)
+			wantXor = Xor(wantXor, ba)
+		}
+		if got := AndReduce(arrs...); !got.Equal(wantAnd) {
+			t.Errorf("%d: got %q, want %q", i, got, wantAnd)
+		}
+		if got := XorReduce(arrs...); !got.Equal(wantXor) {
+			t.Errorf("%d: got %q, want %q", i, got, wantXor)
+		}
+		if got := arrs[0].String(); got != test[0] {
+			t.Errorf("%d: operand modified: got %q", i, got)
+		}
+	}
+}
+
+func TestAndXorReducePanic(t *testing.T) {
+	tests := [][]*BitArray{{}, {New(4), New(4), New(5)}}
+	funcs := []func(arrs ...*BitArray) *BitArray{AndReduce, XorReduce}
+	for i, test := range tests {
+		for j, f := range funcs {
+			func() {
+				defer func() { recover() }()
+				f(test...)
+				t.Errorf("%d/%d: did not panic", i, j)
+			}()
+		}
+	}
+}
+
+func TestTernary(t *testing.T) {
+	a := MustParse("0000111100001111")
+	b := MustParse("0011001100110011")
+	c := MustParse("0101010101010101")
+	tests := []struct {
+		lut  uint8
+		want string
+	}{
+		{0x80, "0000000100000001"},
+		{0xe8, "0001011100010111"},
+		{0xfe, "0111111101111111"},
+		{0x96, "0110100101101001"},
+		{0xca, "0101001101010011"},
+		{0x00, "0000000000000000"},
+		{0xff, "1111111111111111"},
+	}
+	for i, test := range tests {
+		if got := Ternary(a, b, c, test.lut).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+	a, b, c = MustParse("0011110000"), MustParse("0101001100"), MustParse("1100101010")
+	want := And(And(a, b), c)
+	if got := Ternary(a, b, c, 0x80); !got.Equal(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := Ternary(a, b, c, 0xff); got.String() != "1111111111" || got.Count() != 10 {
+		t.Errorf("got %q with count %d", got, got.Count())
+	}
+}
+
+func TestTernaryDiffSize(t *testing.T) {
+	defer func() { recover() }()
+	Ternary(New(4), New(4), New(5), 0)
+	t.Error("did not panic")
+}
+
+func TestReadFrom(t *testing.T) {
+	r := bytes.NewReader([]byte{0b10000001, 0b11, 0b10100101, 0b111})
+	tests := []struct {
+		size int
+		want string
+	}{
+		{10, "1110000001"},
+		{8, "10100101"},
+		{3, "111"},
+	}
+	for i, test := range tests {
+		ba, err := ReadFrom(r, test.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ba.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestReadFromError(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		size int
+		err  error
+	}{
+		{[]byte{}, 8, io.ErrUnexpectedEOF},
+		{[]byte{1}, 10, io.ErrUnexpectedEOF},
+		{[]byte{1, 2, 3}, 25, io.ErrUnexpectedEOF},
+		{[]byte{1, 0b100}, 10, nil},
+	}
+	for i, test := range tests {
+		_, err := ReadFrom(bytes.NewReader(test.b), test.size)
+		if err == nil || test.err != nil && err != test.err {
+			t.Errorf("%d: got %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	tests := []string{"0101", "01010101", "1100000101", "0101010101010101"}
+	var buf bytes.Buffer
+	for i, test := range tests {
+		n, err := MustParse(test).WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := int64((len(test) + 7) / 8); n != want {
+			t.Errorf("%d: got %d, want %d", i, n, want)
+		}
+	}
+	if got, want := buf.Bytes(), []byte{0b0101, 0b01010101, 0b00000101, 0b11, 0b01010101, 0b01010101}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for i, test := range tests {
+		ba, err := ReadFrom(&buf, len(test))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ba.String(); got != test {
+			t.Errorf("%d: got %q, want %q", i, got, test)
+		}
+	}
+}
+
+func TestOuter(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   string
+	}{
+		{"11", "10", "1010"},
+		{"10", "11", "1100"},
+		{"01", "101", "000101"},
+		{"101", "0110", "011000000110"},
+		{"0", "1111", "0000"},
+	}
+	for i, test := range tests {
+		if got := Outer(MustParse(test.s1), MustParse(test.s2)).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestKroneckerOr(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   string
+	}{
+		{"11", "10", "1111"},
+		{"10", "01", "1101"},
+		{"00", "01", "0101"},
+		{"010", "010", "010111010"},
+		{"0", "0000", "0000"},
+	}
+	for i, test := range tests {
+		if got := KroneckerOr(MustParse(test.s1), MustParse(test.s2)).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestConvolve(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   []int
+	}{
+		{"11", "11", []int{1, 2, 1}},
+		{"111", "11", []int{1, 2, 2, 1}},
+		{"101", "1", []int{1, 0, 1}},
+		{"0", "0", []int{0}},
+		{"1011", "0110", []int{0, 1, 2, 1, 1, 1, 0}},
+	}
+	for i, test := range tests {
+		if got := Convolve(MustParse(test.s1), MustParse(test.s2)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	tests := []string{
+		"0101", "01010101", "0101010101", "0101010101010101",
+	}
+	for i, test := range tests {
+		ba1 := MustParse(test)
+		buf, err := ba1.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ba2 := new(BitArray)
+		err = ba2.UnmarshalBinary(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ba2.String(); got != test {
+			t.Errorf("%d: got %q, want %q", i, got, test)
+		}
+	}
+}
+
+func TestFirstNextUnset(t *testing.T) {
+	tests := []struct {
+		s     string
+		from  int
+		first int
+		next  int
+	}{
+		{"1111111111", 0, -1, -1},
+		{"1111111111111111", 3, -1, -1},
+		{"1111111011", 0, 2, 2},
+		{"1111111011", 3, 2, -1},
+		{"0111111111", 0, 9, 9},
+		{"0111111111", 9, 9, 9},
+		{"1111111110", 1, 0, -1},
+		{"0000000000", 5, 0, 5},
+		{"0111111111111111", 0, 15, 15},
+		{"1011111111111111", 7, 14, 14},
+		{"1111", 0, -1, -1},
+		{"110", 1, 0, -1},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got, ok := ba.FirstUnset()
+		if got != test.first || ok != (test.first >= 0) {
+			t.Errorf("%d: got %d and %t, want %d", i, got, ok, test.first)
+		}
+		got, ok = ba.NextUnset(test.from)
+		if got != test.next || ok != (test.next >= 0) {
+			t.Errorf("%d: got %d and %t, want %d", i, got, ok, test.next)
+		}
+	}
+}
+
+func TestNextUnsetPanic(t *testing.T) {
+	for i, from := range []int{-1, 10} {
+		func() {
+			defer func() { recover() }()
+			New(10).NextUnset(from)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestAllocateFree(t *testing.T) {
+	ba := New(10)
+	for want := 0; want < 10; want++ {
+		if got, ok := ba.Allocate(); !ok || got != want {
+			t.Errorf("got %d and %t, want %d and true", got, ok, want)
+		}
+	}
+	if got, ok := ba.Allocate(); ok || got != -1 {
+		t.Errorf("got %d and %t, want -1 and false", got, ok)
+	}
+	ba.Free(7)
+	ba.Free(3)
+	for _, want := range []int{3, 7} {
+		if got, ok := ba.Allocate(); !ok || got != want {
+			t.Errorf("got %d and %t, want %d and true", got, ok, want)
+		}
+	}
+	if !ba.AllOne() {
+		t.Errorf("got %q, want all ones", ba)
+	}
+}
+
+func TestFreePanic(t *testing.T) {
+	tests := []struct {
+		s   string
+		idx int
+	}{
+		{"1101", 1},
+		{"1101", -1},
+		{"1101", 4},
+	}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			MustParse(test.s).Free(test.idx)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
+
+func TestHighestSet(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		ok   bool
+	}{
+		{"0011000100", 7, true},
+		{"0000000000", -1, false},
+		{"1000000001", 9, true},
+		{"0000000001", 0, true},
+		{"0001", 0, true},
+		{"1000", 3, true},
+		{"0000000010000000", 7, true},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got, ok := ba.HighestSet()
+		if got != test.want || ok != test.ok {
+			t.Errorf("%d: got %d and %t, want %d and %t", i, got, ok, test.want, test.ok)
+		}
+		if got := ba.BitLen(); got != test.want+1 {
+			t.Errorf("%d: got %d, want %d", i, got, test.want+1)
+		}
+	}
+}
+
+func TestLowestSet(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		ok   bool
+	}{
+		{"0011000100", 2, true},
+		{"0000000000", -1, false},
+		{"1000000001", 0, true},
+		{"1000000000", 9, true},
+		{"0001", 0, true},
+		{"1000", 3, true},
+		{"0000000100000000", 8, true},
+	}
+	for i, test := range tests {
+		got, ok := MustParse(test.s).LowestSet()
+		if got != test.want || ok != test.ok {
+			t.Errorf("%d: got %d and %t, want %d and %t", i, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestLowestSetTrailingZeros(t *testing.T) {
+	empty := New(10)
+	if idx, ok := empty.LowestSet(); ok || idx != -1 {
+		t.Errorf("got %d and %t, want -1 and false", idx, ok)
+	}
+	if got := empty.TrailingZeros(); got != 10 {
+		t.Errorf("got %d, want 10", got)
+	}
+	ba := MustParse("0000000001")
+	if idx, ok := ba.LowestSet(); !ok || idx != 0 {
+		t.Errorf("got %d and %t, want 0 and true", idx, ok)
+	}
+	if got := ba.TrailingZeros(); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"00001010", "1010"},
+		{"0000", "0"},
+		{"0001", "1"},
+		{"1000", "1000"},
+		{"0000001100000101", "1100000101"},
+		{"0000000000000000", "0"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got := ba.Trim()
+		if got.String() != test.want || got.Size() != len(test.want) {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if ba.String() != test.s {
+			t.Errorf("%d: bit array modified: got %q", i, ba)
+		}
+	}
+}
+
+func TestSetBitsBounds(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end int
+		ok         bool
+	}{
+		{"0011000100", 2, 8, true},
+		{"0000000000", 0, 0, false},
+		{"1000000001", 0, 10, true},
+		{"0000100000", 5, 6, true},
+		{"0001", 0, 1, true},
+		{"1000", 3, 4, true},
+	}
+	for i, test := range tests {
+		start, end, ok := MustParse(test.s).SetBitsBounds()
+		if start != test.start || end != test.end || ok != test.ok {
+			t.Errorf("%d: got %d, %d, and %t, want %d, %d, and %t",
+				i, start, end, ok, test.start, test.end, test.ok)
+		}
+	}
+}
+
+func TestMedianSet(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		ok   bool
+	}{
+		{"0100110101", 4, true},
+		{"0000000001", 0, true},
+		{"1000000001", 9, true},
+		{"0000000000", -1, false},
+	}
+	for i, test := range tests {
+		got, ok := MustParse(test.s).MedianSet()
+		if got != test.want || ok != test.ok {
+			t.Errorf("%d: got %d and %t, want %d and %t", i, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestBucketCounts(t *testing.T) {
+	ba := New(100, 0, 1, 24, 25, 26, 49, 50, 74, 75, 98, 99)
+	want := []int{3, 3, 2, 3}
+	got := ba.BucketCounts(4)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	sum := 0
+	for _, n := range got {
+		sum += n
+	}
+	if sum != ba.Count() {
+		t.Errorf("got sum %d, want %d", sum, ba.Count())
+	}
+	tests := []struct {
+		s    string
+		n    int
+		want []int
+	}{
+		{"1111111111", 3, []int{3, 3, 4}},
+		{"0000000101", 1, []int{2}},
+		{"1011", 6, []int{0, 0, 0, 0, 0, 3}},
+	}
+	for i, test := range tests {
+		if got := MustParse(test.s).BucketCounts(test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestBucketCountsPanic(t *testing.T) {
+	defer func() { recover() }()
+	New(4).BucketCounts(0)
+	t.Error("did not panic")
+}
+
+func TestBigInt(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"0000", "0"},
+		{"1010", "10"},
+		{"1111111111", "1023"},
+		{"10000000000000000000000000000000000000000000000000000000000000000", "18446744073709551616"},
+		{"11111111111111111111111111111111111111111111111111111111111111111111111111111111", "1208925819614629174706175"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got := ba.BigInt()
+		if got.String() != test.want {
+			t.Errorf("%d: got %s, want %s", i, got, test.want)
+		}
+		if ba2 := FromBigInt(ba.Size(), got); !ba2.Equal(ba) {
+			t.Errorf("%d: got %q, want %q", i, ba2, ba)
+		}
+	}
+}
+
+func TestFromBigInt(t *testing.T) {
+	v, _ := new(big.Int).SetString("1208925819614629174706175", 10) // 80 bits set
+	tests := []struct {
+		size int
+		want string
+	}{
+		{4, "1111"},
+		{10, "1111111111"},
+		{82, "0011111111111111111111111111111111111111111111111111111111111111111111111111111111"},
+	}
+	for i, test := range tests {
+		if got := FromBigInt(test.size, v).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestFromBigIntPanic(t *testing.T) {
+	defer func() { recover() }()
+	FromBigInt(4, big.NewInt(-1))
+	t.Error("did not panic")
+}
+
+func TestDigits(t *testing.T) {
+	tests := []struct {
+		s    string
+		base int
+		want []int
+	}{
+		{"0000001010", 10, []int{0, 1}},
+		{"0000001010", 16, []int{10}},
+		{"0000001010", 2, []int{0, 1, 0, 1}},
+		{"0000", 10, []int{0}},
+		{"1111111111", 36, []int{15, 28}},
+		{"10000000000000000000000000000000000000000000000000000000000000000", 10,
+			[]int{6, 1, 6, 1, 5, 5, 9, 0, 7, 3, 7, 0, 4, 4, 7, 6, 4, 4, 8, 1}},
+	}
+	for i, test := range tests {
+		got, err := MustParse(test.s).Digits(test.base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestDigitsError(t *testing.T) {
+	tests := []int{-1, 0, 1, 37}
+	for i, test := range tests {
+		if _, err := New(4).Digits(test); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestUint64(t *testing.T) {
+	tests := []struct {
+		size int
+		v    uint64
+	}{
+		{1, 0},
+		{1, 1},
+		{8, 0xa5},
+		{8, 0xff},
+		{63, 1<<63 - 1},
+		{63, 0x5555555555555555},
+		{64, 1<<64 - 1},
+		{64, 0x8000000000000001},
+	}
+	for i, test := range tests {
+		ba := FromUint64(test.size, test.v)
+		if ba.Size() != test.size {
+			t.Errorf("%d: got size %d, want %d", i, ba.Size(), test.size)
+		}
+		got, err := ba.Uint64()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.v {
+			t.Errorf("%d: got %#x, want %#x", i, got, test.v)
+		}
+	}
+}
+
+func TestUint64Error(t *testing.T) {
+	if _, err := New(65).Uint64(); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestFromUint64(t *testing.T) {
+	tests := []struct {
+		size int
+		v    uint64
+		want string
+	}{
+		{1, 0b11, "1"},
+		{4, 0b110101, "0101"},
+		{10, 1<<64 - 1, "1111111111"},
+		{12, 0xf0f, "111100001111"},
+	}
+	for i, test := range tests {
+		if got := FromUint64(test.size, test.v).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestFromUint64Panic(t *testing.T) {
+	defer func() { recover() }()
+	FromUint64(65, 0)
+	t.Error("did not panic")
+}
+
+func TestKeep(t *testing.T) {
+	tests := []struct {
+		s    string
+		idx  []int
+		want string
+	}{
+		{"1111", []int{0, 2}, "0101"},
+		{"1111", []int{}, "0000"},
+		{"1010", []int{0, 1, 2}, "0010"},
+		{"1000000001", []int{9, 9, 8}, "1000000000"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		if got := ba.Keep(test.idx...).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got := ba.String(); got != test.s {
+			t.Errorf("%d: receiver modified: got %q, want %q", i, got, test.s)
+		}
+	}
+}
+
+func TestKeepIdx(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Keep(4)
+	t.Error("did not panic")
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []byte
+	}{
+		{"0101", []byte{0b0101}},
+		{"01010101", []byte{0b01010101}},
+		{"1100000001", []byte{1, 0b11}},
+		{"0000000110000001", []byte{0b10000001, 1}},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		got := ba.Bytes()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+		}
+		got[0] = ^got[0]
+		if s := ba.String(); s != test.s {
+			t.Errorf("%d: got %q, want %q", i, s, test.s)
+		}
+	}
+}
+
+func TestForEachByte(t *testing.T) {
+	tests := []string{"0101", "1100000101", "0000000110000001", "11111111111111111"}
+	for i, test := range tests {
+		ba := MustParse(test)
+		setPadding(ba, 0xff)
+		var got []byte
+		ba.ForEachByte(func(j int, b byte) bool {
+			if j != len(got) {
+				t.Errorf("%d: got index %d, want %d", i, j, len(got))
+			}
+			got = append(got, b)
+			return true
+		})
+		if want := MustParse(test).Bytes(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d: got %v, want %v", i, got, want)
+		}
+	}
+	cnt := 0
+	New(100).ForEachByte(func(int, byte) bool {
+		cnt++
+		return cnt < 3
+	})
+	if cnt != 3 {
+		t.Errorf("got %d calls, want 3", cnt)
+	}
+}
+
+func TestFromBytesLE(t *testing.T) {
+	tests := [][]byte{
+		{0},
+		{0b10100101},
+		{0b10000001, 1},
+		{1, 2, 3, 4, 5, 6, 7, 8, 9},
+	}
+	for i, test := range tests {
+		ba, err := FromBytesLE(len(test)*8, test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ba.Bytes(); !reflect.DeepEqual(got, test) {
+			t.Errorf("%d: got %v, want %v", i, got, test)
+		}
+	}
+	ba, err := FromBytesLE(10, []byte{1, 0b11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ba.String(), "1100000001"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFromBytesLEError(t *testing.T) {
+	tests := []struct {
+		size int
+		b    []byte
+	}{
+		{10, []byte{1}},
+		{10, []byte{1, 2, 3}},
+		{10, []byte{1, 0b100}},
+		{4, []byte{0b10000}},
+		{math.MaxInt / 2, []byte{1}},
+		{math.MaxInt, []byte{1}},
+	}
+	for i, test := range tests {
+		if _, err := FromBytesLE(test.size, test.b); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestBytesMSB(t *testing.T) {
+	tests := []struct {
+		s        string
+		lsb, msb []byte
+	}{
+		{"0001", []byte{0b0001}, []byte{0b10000000}},
+		{"10100101", []byte{0b10100101}, []byte{0b10100101}},
+		{"00000011", []byte{0b11}, []byte{0b11000000}},
+		{"1100000001", []byte{1, 0b11}, []byte{0b10000000, 0b11000000}},
+		{"0100000000000010", []byte{0b10, 0b1000000}, []byte{0b1000000, 0b10}},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		if got := ba.Bytes(); !reflect.DeepEqual(got, test.lsb) {
+			t.Errorf("%d: got %v, want %v", i, got, test.lsb)
+		}
+		got := ba.BytesMSB()
+		if !reflect.DeepEqual(got, test.msb) {
+			t.Errorf("%d: got %v, want %v", i, got, test.msb)
+		}
+		ba2, err := FromBytesMSB(ba.Size(), got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ba2.Equal(ba) {
+			t.Errorf("%d: got %q, want %q", i, ba2, ba)
+		}
+	}
+}
+
+func TestFromBytesMSBError(t *testing.T) {
+	tests := []struct {
+		size int
+		b    []byte
+	}{
+		{10, []byte{1}},
+		{10, []byte{1, 2, 3}},
+		{10, []byte{1, 0b100000}},
+		{4, []byte{0b1000}},
+	}
+	for i, test := range tests {
+		if _, err := FromBytesMSB(test.size, test.b); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestDrop(t *testing.T) {
+	tests := []struct {
+		s    string
+		idx  []int
+		want string
+	}{
+		{"1111", []int{0, 2}, "1010"},
+		{"1111", []int{}, "1111"},
+		{"1010", []int{0, 1, 2}, "1000"},
+		{"1000000001", []int{9, 9, 8}, "0000000001"},
+	}
+	for i, test := range tests {
+		ba := MustParse(test.s)
+		if got := ba.Drop(test.idx...).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		if got := ba.String(); got != test.s {
+			t.Errorf("%d: receiver modified: got %q, want %q", i, got, test.s)
+		}
+	}
+}
+
+func TestDropIdx(t *testing.T) {
+	defer func() { recover() }()
+	New(4).Drop(-1)
+	t.Error("did not panic")
+}
+
+func TestMarshalBinary(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []byte
+	}{
+		{"0101", []byte{binaryVersion, 4, 0b0101}},
+		{"1100000101", []byte{binaryVersion, 10, 0b101, 0b11}},
+		{strings.Repeat("1", 200), append([]byte{binaryVersion, 0xc8, 1}, bytes.Repeat([]byte{0xff}, 25)...)},
+	}
+	for i, test := range tests {
+		got, err := MustParse(test.s).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestUnmarshalBinaryGob(t *testing.T) {
+	want := "1100000101"
+	data := []byte{0x3, 0x4, 0x0, 0x14, 0x5, 0xa, 0x0, 0x2, 0x5, 0x3}
+	ba := new(BitArray)
+	if err := ba.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := ba.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnmarshalBinaryGobError(t *testing.T) {
+	tests := []struct {
+		size int
+		data []uint8
+	}{
+		{100, []uint8{1}},
+		{10, []uint8{1, 2, 3}},
+		{4, []uint8{0x10}},
+		{0, []uint8{1}},
+		{-8, []uint8{1}},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		enc := gob.NewEncoder(&buf)
+		if err := enc.Encode(test.size); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(test.data); err != nil {
+			t.Fatal(err)
+		}
+		ba := MustParse("0101")
+		if err := ba.UnmarshalBinary(buf.Bytes()); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+		if got := ba.String(); got != "0101" {
+			t.Errorf("%d: receiver modified: got %q", i, got)
+		}
+	}
+}
+
+func TestUnmarshalBinaryError(t *testing.T) {
+	tests := [][]byte{
+		{},
+		{binaryVersion},
+		{binaryVersion, 0},
+		{binaryVersion, 10, 1},
+		{binaryVersion, 4, 0x10},
+		{binaryVersion, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3f, 1},
+		{0x3, 0x4, 0x0},
+	}
+	for i, test := range tests {
+		if err := new(BitArray).UnmarshalBinary(test); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestGob(t *testing.T) {
+	type record struct {
+		Name string
+		Mask *BitArray
+	}
+	tests := []string{"0101", "1100000101", strings.Repeat("10", 100)}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		want := record{"mask", MustParse(test)}
+		if err := gob.NewEncoder(&buf).Encode(want); err != nil {
+			t.Fatal(err)
+		}
+		var got record
+		if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != want.Name || got.Mask == nil || !got.Mask.Equal(want.Mask) {
+			t.Errorf("%d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestGobDecodeError(t *testing.T) {
+	data := []byte{binaryVersion, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3f, 1}
+	if err := new(BitArray).GobDecode(data); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestMarshalUnmarshalText(t *testing.T) {
+	tests := []string{
+		"0101", "01010101", "0101010101", "0101010101010101",
+	}
+	for i, test := range tests {
+		buf, err := MustParse(test).MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != test {
+			t.Errorf("%d: got %q, want %q", i, buf, test)
+		}
+		ba := new(BitArray)
+		if err = ba.UnmarshalText([]byte(test[:2] + " " + test[2:])); err != nil {
+			t.Fatal(err)
+		}
+		if got := ba.String(); got != test {
+			t.Errorf("%d: got %q, want %q", i, got, test)
+		}
+	}
+}
+
+func TestUnmarshalTextError(t *testing.T) {
+	tests := []string{"", " ", "012"}
+	for i, test := range tests {
+		if err := new(BitArray).UnmarshalText([]byte(test)); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestTextJSON(t *testing.T) {
+	type S struct {
+		Name string
+		Mask *BitArray
+	}
+	s1 := S{"test", MustParse("1100000101")}
+	buf, err := json.Marshal(s1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), `{"Name":"test","Mask":"1100000101"}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	var s2 S
+	if err = json.Unmarshal(buf, &s2); err != nil {
+		t.Fatal(err)
+	}
+	if s2.Name != s1.Name || !s2.Mask.Equal(s1.Mask) {
+		t.Errorf("got %v, want %v", s2, s1)
+	}
+}
+
+func TestMarshalUnmarshalJSON(t *testing.T) {
+	tests := []string{
+		"0", "0101", "01010101", "0101010101", "0101010101010101",
+	}
+	for i, test := range tests {
+		buf, err := MustParse(test).MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `"` + test + `"`; string(buf) != want {
+			t.Errorf("%d: got %s, want %s", i, buf, want)
+		}
+		ba := new(BitArray)
+		if err = ba.UnmarshalJSON(buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := ba.String(); got != test {
+			t.Errorf("%d: got %q, want %q", i, got, test)
+		}
+	}
+}
+
+func TestUnmarshalJSONNull(t *testing.T) {
+	want := "1010"
+	ba := MustParse(want)
+	if err := ba.UnmarshalJSON([]byte("null")); err != nil {
+		t.Fatal(err)
+	}
+	if got := ba.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUnmarshalJSONError(t *testing.T) {
+	tests := []string{`"012"`, `""`, `1010`, `true`, `["1010"]`, `{}`}
+	for i, test := range tests {
+		if err := new(BitArray).UnmarshalJSON([]byte(test)); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestDebug(t *testing.T) {
+	ba := MustParse("1100000101")
+	want := `size: 10, bytes: 2
+[0] 0x05 00000101 bits 0-7
+[1] 0x03 00000011 bits 8-9, padding masked`
+	if got := ba.Debug(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	setPadding(ba, 0b10000)
+	if got := ba.Debug(); !strings.HasSuffix(got, "padding unmasked") {
+		t.Errorf("got\n%s", got)
+	}
+	if got := MustParse("10000001").Debug(); strings.Contains(got, "padding") ||
+		!strings.Contains(got, "[0] 0x81 10000001 bits 0-7") {
+		t.Errorf("got\n%s", got)
+	}
+}
+
+func TestDump(t *testing.T) {
+	ba := New(76)
+	for i := 0; i < ba.Size(); i += 5 {
+		ba.Set(i)
+	}
+	setPadding(ba, 0xf0)
+	var buf bytes.Buffer
+	if err := ba.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "00000000  21 84 10 42 08 21 84 10  bits 0-63\n" +
+		"00000008  42 08                    bits 64-75\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestDumpError(t *testing.T) {
+	if err := New(10).Dump(errWriter{}); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   string
+	}{
+		{"1100000101", "1100001001", `size:             10, 10
+count:            4, 4
+hamming distance: 2
+first difference: 2
+1100000101
+1100001001
+      ^^`},
+		{"0101", "0101", `size:             4, 4
+count:            2, 2
+hamming distance: 0
+first difference: none
+0101
+0101
+`},
+		{"110101", "0101", `size:             6, 4
+count:            4, 2
+hamming distance: n/a
+first difference: 4
+110101
+  0101
+^^`},
+	}
+	for i, test := range tests {
+		got := MustParse(test.s1).Report(MustParse(test.s2))
+		if got != test.want {
+			t.Errorf("%d: got\n%s\nwant\n%s", i, got, test.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   bool
+	}{
+		{"0101", "0101", true},
+		{"1100000101", "1100000101", true},
+		{"0101", "00000101", false},
+		{"0101", "0100", false},
+		{"0", "00", false},
+		{"00000000", "000000000", false},
+	}
+	for i, test := range tests {
+		k1, k2 := MustParse(test.s1).Key(), MustParse(test.s2).Key()
+		if got := k1 == k2; got != test.want {
+			t.Errorf("%d: got %t, want %t", i, got, test.want)
+		}
+	}
+	if got := len(New(1000).Key()); got != 127 {
+		t.Errorf("got length %d, want 127", got)
+	}
+	m := map[string]int{}
+	m[MustParse("0101").Key()]++
+	m[MustParse("0101").Key()]++
+	m[MustParse("00000101").Key()]++
+	if len(m) != 2 || m[MustParse("0101").Key()] != 2 {
+		t.Errorf("got %v", m)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	ba := MustParse("1100000101")
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%b", "1100000101"},
+		{"%s", "1100000101"},
+		{"%v", "1100000101"},
+		{"%o", "1405"},
+		{"%x", "305"},
+		{"%X", "305"},
+		{"%#b", "0b1100000101"},
+		{"%#o", "01405"},
+		{"%#x", "0x305"},
+		{"%#X", "0X305"},
+		{"%12b", "  1100000101"},
+		{"%-12b|", "1100000101  |"},
+		{"%012b", "001100000101"},
+		{"%06x", "000305"},
+		{"%#06x", "0x0305"},
+		{"%.5x", "00305"},
+		{"%8.5x", "   00305"},
+		{"%4b", "1100000101"},
+		{"%q", `"1100000101"`},
+		{"%14q", `  "1100000101"`},
+		{"%d", "%!d(*bitarray.BitArray=1100000101)"},
+	}
+	for i, test := range tests {
+		if got := fmt.Sprintf(test.format, ba); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+	tests2 := []struct {
+		s          string
+		wantO      string
+		wantX      string
+		wantUpperX string
+	}{
+		{"0", "0", "0", "0"},
+		{"1111", "17", "f", "F"},
+		{"10101011", "253", "ab", "AB"},
+		{"1111111111111111", "177777", "ffff", "FFFF"},
+	}
+	for i, test := range tests2 {
+		ba := MustParse(test.s)
+		got := fmt.Sprintf("%o %x %X", ba, ba, ba)
+		if want := test.wantO + " " + test.wantX + " " + test.wantUpperX; got != want {
+			t.Errorf("%d: got %q, want %q", i, got, want)
 		}
 	}
 }
@@ -0,0 +1,57 @@
+package bitarray
+
+// Builder is used to efficiently build a BitArray bit by bit. The zero value is
+// ready to use. The first bit written gets index 0.
+type Builder struct {
+	size int
+	data []byte
+}
+
+// Len returns the number of bits written so far.
+func (b *Builder) Len() int {
+	return b.size
+}
+
+// WriteBit appends a single bit.
+func (b *Builder) WriteBit(v bool) {
+	n, i := b.size/bitsN, b.size%bitsN
+	if i == 0 {
+		b.data = append(b.data, 0)
+	}
+	if v {
+		b.data[n] |= 1 << i
+	}
+	b.size++
+}
+
+// WriteBits appends the n low bits of v, starting with bit 0 of v.
+// Panics if n < 0 or n > 64.
+func (b *Builder) WriteBits(v uint64, n int) {
+	if n < 0 || n > 64 {
+		panic("n out of range")
+	}
+	for range n {
+		b.WriteBit(v&1 != 0)
+		v >>= 1
+	}
+}
+
+// WriteByte appends the 8 bits of c, starting with bit 0 of c. It always returns nil;
+// the error is only there to satisfy [io.ByteWriter].
+func (b *Builder) WriteByte(c byte) error {
+	if b.size%bitsN == 0 {
+		b.data = append(b.data, c)
+		b.size += bitsN
+		return nil
+	}
+	b.WriteBits(uint64(c), bitsN)
+	return nil
+}
+
+// BitArray returns a new BitArray with all bits written so far. The builder can
+// still be used afterwards. Panics if no bits were written.
+func (b *Builder) BitArray() *BitArray {
+	ba := New(b.size)
+	copy(ba.data, b.data)
+	return ba
+}
@@ -0,0 +1,56 @@
+package bitarray
+
+import (
+	"io"
+	"testing"
+)
+
+var _ io.ByteWriter = (*Builder)(nil)
+
+func TestBuilder(t *testing.T) {
+	want := MustParse("10110")
+	var b Builder
+	for _, v := range []bool{false, true, true, false, true} {
+		b.WriteBit(v)
+	}
+	if got := b.BitArray(); !got.Equal(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	b = Builder{}
+	b.WriteBits(0b10110, 5)
+	if got := b.BitArray(); !got.Equal(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := b.Len(); got != 5 {
+		t.Errorf("got %d, want 5", got)
+	}
+}
+
+func TestBuilderMixed(t *testing.T) {
+	var b Builder
+	b.WriteByte(0b10000001)
+	b.WriteBit(true)
+	b.WriteByte(0b11000000)
+	b.WriteBits(0, 3)
+	b.WriteBits(^uint64(0), 64)
+	want := "1111111111111111111111111111111111111111111111111111111111111111" +
+		"000" + "11000000" + "1" + "10000001"
+	if got := b.BitArray().String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestBuilderPanic(t *testing.T) {
+	tests := []func(){
+		func() { new(Builder).WriteBits(0, -1) },
+		func() { new(Builder).WriteBits(0, 65) },
+		func() { new(Builder).BitArray() },
+	}
+	for i, f := range tests {
+		func() {
+			defer func() { recover() }()
+			f()
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
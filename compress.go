@@ -0,0 +1,215 @@
+package bitarray
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNotSparse is returned by [BitArray.PackSparse] if the sparse encoding is not
+// smaller than the packed bytes.
+var ErrNotSparse = errors.New("bit array is not sparse")
+
+// PackSparse encodes the bit array as the size followed by the distances between
+// consecutive set bits, all as unsigned varints. Returns [ErrNotSparse] if the
+// encoding is not smaller than the size followed by the packed bytes.
+func (ba *BitArray) PackSparse() ([]byte, error) {
+	b := ba.appendSparse(nil)
+	if len(b) >= uvarintLen(ba.size)+len(ba.data) {
+		return nil, ErrNotSparse
+	}
+	return b, nil
+}
+
+// MaxDecodeSize is the maximum size of a bit array decoded by [UnpackSparse] and
+// [RLEDecode] (and by [UnmarshalCompact] for these formats), i.e. at most 2 MiB are
+// allocated. The length of these encodings does not limit the size, so larger sizes
+// are rejected with an error instead of allocating the memory.
+const MaxDecodeSize = 1 << 24
+
+// UnpackSparse creates a new BitArray from the encoding returned by [BitArray.PackSparse].
+// Returns an error if the size is greater than [MaxDecodeSize].
+func UnpackSparse(b []byte) (*BitArray, error) {
+	size, b, err := readMaxSize(b)
+	if err != nil {
+		return nil, err
+	}
+	var idx []int
+	prev := -1
+	for len(b) > 0 {
+		d, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errors.New("invalid varint")
+		}
+		b = b[n:]
+		if d >= uint64(size-prev-1) {
+			return nil, errors.New("index out of range")
+		}
+		prev += int(d) + 1
+		idx = append(idx, prev)
+	}
+	ba := New(size)
+	for _, i := range idx {
+		ba.set(i)
+	}
+	return ba, nil
+}
+
+func (ba *BitArray) appendSparse(b []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(ba.size))
+	prev := -1
+	for i := 0; i < ba.size; i++ {
+		if ba.get(i) {
+			b = binary.AppendUvarint(b, uint64(i-prev-1))
+			prev = i
+		}
+	}
+	return b
+}
+
+// readSize reads the size as an unsigned varint and returns it with the remaining bytes.
+func readSize(b []byte) (int, []byte, error) {
+	size, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, nil, errors.New("invalid size")
+	}
+	if size == 0 || size > math.MaxInt {
+		return 0, nil, errors.New("size out of range")
+	}
+	return int(size), b[n:], nil
+}
+
+// readMaxSize is like readSize but also returns an error if the size is greater
+// than MaxDecodeSize.
+func readMaxSize(b []byte) (int, []byte, error) {
+	size, b, err := readSize(b)
+	if err == nil && size > MaxDecodeSize {
+		err = fmt.Errorf("size must be <= %d: %d", MaxDecodeSize, size)
+	}
+	return size, b, err
+}
+
+func uvarintLen(x int) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], uint64(x))
+}
+
+const (
+	compactDense byte = iota
+	compactRLE
+	compactSparse
+)
+
+// MarshalCompact encodes the bit array in the smallest of three formats: the packed
+// bytes, a run-length encoding, or the sparse encoding of [BitArray.PackSparse].
+// The first byte of the result identifies the format.
+func (ba *BitArray) MarshalCompact() []byte {
+	best := ba.appendDense([]byte{compactDense})
+	if b := ba.appendRLE([]byte{compactRLE}); len(b) < len(best) {
+		best = b
+	}
+	if b := ba.appendSparse([]byte{compactSparse}); len(b) < len(best) {
+		best = b
+	}
+	return best
+}
+
+// UnmarshalCompact creates a new BitArray from the encoding returned by
+// [BitArray.MarshalCompact].
+func UnmarshalCompact(b []byte) (*BitArray, error) {
+	if len(b) == 0 {
+		return nil, errors.New("empty data")
+	}
+	switch b[0] {
+	case compactDense:
+		return decodeDense(b[1:])
+	case compactRLE:
+		return decodeRLE(b[1:])
+	case compactSparse:
+		return UnpackSparse(b[1:])
+	}
+	return nil, fmt.Errorf("unknown format: %d", b[0])
+}
+
+// RLEEncode encodes the bit array as the size, the value of the bit at index 0, and
+// the lengths of the runs of equal bits (see [BitArray.RunLengths]). This is small for
+// bit arrays with long runs of equal bits.
+func (ba *BitArray) RLEEncode() []byte {
+	return ba.appendRLE(nil)
+}
+
+// RLEDecode creates a new BitArray from the encoding returned by [BitArray.RLEEncode].
+// Returns an error if the size is greater than [MaxDecodeSize].
+func RLEDecode(b []byte) (*BitArray, error) {
+	return decodeRLE(b)
+}
+
+func (ba *BitArray) appendDense(b []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(ba.size))
+	return append(b, ba.data...)
+}
+
+func decodeDense(b []byte) (*BitArray, error) {
+	size, b, err := readSize(b)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytesLE(size, b)
+}
+
+// appendRLE appends the size, the value of the bit at index 0 as a byte, and the
+// lengths of the runs of equal bits starting at index 0 as unsigned varints.
+func (ba *BitArray) appendRLE(b []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(ba.size))
+	if ba.get(0) {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	for _, n := range ba.RunLengths() {
+		b = binary.AppendUvarint(b, uint64(n))
+	}
+	return b
+}
+
+func decodeRLE(b []byte) (*BitArray, error) {
+	size, b, err := readMaxSize(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 || b[0] > 1 {
+		return nil, errors.New("invalid start value")
+	}
+	v := b[0] == 1
+	b = b[1:]
+	var runs []int
+	idx := 0
+	for len(b) > 0 {
+		n, m := binary.Uvarint(b)
+		if m <= 0 {
+			return nil, errors.New("invalid varint")
+		}
+		b = b[m:]
+		if n == 0 || n > uint64(size-idx) {
+			return nil, errors.New("invalid run length")
+		}
+		runs = append(runs, int(n))
+		idx += int(n)
+	}
+	if idx != size {
+		return nil, errors.New("run lengths do not match size")
+	}
+	ba := New(size)
+	idx = 0
+	for _, n := range runs {
+		if v {
+			for i := idx; i < idx+n; i++ {
+				ba.set(i)
+			}
+		}
+		idx += n
+		v = !v
+	}
+	return ba, nil
+}
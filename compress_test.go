@@ -0,0 +1,221 @@
+package bitarray
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestPackUnpackSparse(t *testing.T) {
+	tests := []*BitArray{
+		New(10000, 0, 17, 4000, 9998, 9999),
+		New(10000),
+		New(100, 99),
+		New(64, 0),
+	}
+	for i, test := range tests {
+		b, err := test.PackSparse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ba, err := UnpackSparse(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ba.Equal(test) {
+			t.Errorf("%d: got %q, want %q", i, ba, test)
+		}
+	}
+	b, _ := tests[0].PackSparse()
+	if len(b) > 10 {
+		t.Errorf("got %d bytes, want <= 10", len(b))
+	}
+}
+
+func TestPackSparseNotSparse(t *testing.T) {
+	tests := []string{"1", "0101010101", "1111111111111111"}
+	for i, test := range tests {
+		if _, err := MustParse(test).PackSparse(); !errors.Is(err, ErrNotSparse) {
+			t.Errorf("%d: got %v, want %v", i, err, ErrNotSparse)
+		}
+	}
+}
+
+// MaxDecodeSize must fit in an int on 32-bit platforms.
+const _ int32 = MaxDecodeSize
+
+func TestMaxDecodeSize(t *testing.T) {
+	b := binary.AppendUvarint(nil, MaxDecodeSize)
+	if _, err := UnpackSparse(b); err != nil {
+		t.Error(err)
+	}
+	b = binary.AppendUvarint(nil, MaxDecodeSize+1)
+	if _, err := UnpackSparse(b); err == nil {
+		t.Error("no error")
+	}
+	if _, err := UnmarshalCompact(append([]byte{compactSparse}, b...)); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestUnpackSparseError(t *testing.T) {
+	tests := [][]byte{
+		{},
+		{0},
+		{0x80},
+		{10, 10},
+		{10, 5, 4},
+		{10, 0x80},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3f},
+		{0x81, 0x80, 0x80, 0x80, 0x10},
+	}
+	for i, test := range tests {
+		if _, err := UnpackSparse(test); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestMarshalUnmarshalCompact(t *testing.T) {
+	clustered := New(1000)
+	for i := 100; i < 900; i++ {
+		clustered.Set(i)
+	}
+	full := New(1000)
+	full.SetAll()
+	tests := []struct {
+		ba     *BitArray
+		format byte
+	}{
+		{MustParse("0101010101010101"), compactDense},
+		{MustParse("1"), compactDense},
+		{MustParse("1100101001"), compactDense},
+		{clustered, compactRLE},
+		{full, compactRLE},
+		{New(1000), compactSparse},
+		{New(10000, 3, 5000, 9999), compactSparse},
+	}
+	for i, test := range tests {
+		b := test.ba.MarshalCompact()
+		if b[0] != test.format {
+			t.Errorf("%d: got format %d, want %d", i, b[0], test.format)
+		}
+		ba, err := UnmarshalCompact(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ba.Equal(test.ba) {
+			t.Errorf("%d: got %q, want %q", i, ba, test.ba)
+		}
+	}
+}
+
+func TestUnmarshalCompactAll(t *testing.T) {
+	tests := []string{"0", "1", "0110", "11110000", "1100101001", "0000000001110000"}
+	for i, test := range tests {
+		ba := MustParse(test)
+		for _, b := range [][]byte{
+			ba.appendDense([]byte{compactDense}),
+			ba.appendRLE([]byte{compactRLE}),
+			ba.appendSparse([]byte{compactSparse}),
+		} {
+			got, err := UnmarshalCompact(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != test {
+				t.Errorf("%d/%d: got %q, want %q", i, b[0], got, test)
+			}
+		}
+	}
+}
+
+func TestUnmarshalCompactError(t *testing.T) {
+	tests := [][]byte{
+		{},
+		{3, 4, 0},
+		{compactDense},
+		{compactDense, 10, 1},
+		{compactDense, 4, 0x10},
+		{compactRLE, 4},
+		{compactRLE, 4, 2, 4},
+		{compactRLE, 4, 0, 2},
+		{compactRLE, 4, 0, 2, 3},
+		{compactRLE, 4, 0, 0, 4},
+		{compactSparse, 4, 4},
+		{compactSparse, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3f},
+		{compactRLE, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3f, 0},
+	}
+	for i, test := range tests {
+		if _, err := UnmarshalCompact(test); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestRLEEncodeDecode(t *testing.T) {
+	clustered := New(1000)
+	for i := 100; i < 900; i++ {
+		clustered.Set(i)
+	}
+	random := New(1000)
+	random.RandomFill(rand.New(rand.NewSource(1)), 0.5)
+	tests := []*BitArray{
+		clustered,
+		random,
+		New(1000),
+		New(1000, 999),
+		MustParse("1"),
+		MustParse("0101010101"),
+	}
+	for i, test := range tests {
+		ba, err := RLEDecode(test.RLEEncode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ba.Equal(test) {
+			t.Errorf("%d: got %q, want %q", i, ba, test)
+		}
+	}
+	if got := len(clustered.RLEEncode()); got > 10 {
+		t.Errorf("got %d bytes, want <= 10", got)
+	}
+}
+
+func TestRLEDecodeError(t *testing.T) {
+	tests := [][]byte{
+		{},
+		{4},
+		{4, 2, 4},
+		{4, 0, 2},
+		{4, 0, 2, 3},
+		{4, 0, 0, 4},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3f, 0},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3f, 0,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x3f},
+		{0x81, 0x80, 0x80, 0x80, 0x10, 0, 0x81, 0x80, 0x80, 0x80, 0x10},
+	}
+	for i, test := range tests {
+		if _, err := RLEDecode(test); err == nil {
+			t.Errorf("%d: no error", i)
+		}
+	}
+}
+
+func TestRLEDecodeMaxSize(t *testing.T) {
+	rle := func(size int) []byte {
+		b := binary.AppendUvarint(nil, uint64(size))
+		return binary.AppendUvarint(append(b, 0), uint64(size))
+	}
+	if _, err := RLEDecode(rle(MaxDecodeSize)); err != nil {
+		t.Error(err)
+	}
+	b := rle(MaxDecodeSize + 1)
+	if _, err := RLEDecode(b); err == nil {
+		t.Error("no error")
+	}
+	if _, err := UnmarshalCompact(append([]byte{compactRLE}, b...)); err == nil {
+		t.Error("no error")
+	}
+}
@@ -0,0 +1,32 @@
+package bitarray
+
+// CRC computes a cyclic redundancy check over a message that is built from bit arrays.
+type CRC struct {
+	poly *BitArray
+	rem  *BitArray
+}
+
+// NewCRC creates a new CRC with the generator polynomial poly where the bit at index i
+// is the coefficient of x^i. Panics if poly.Size() < 2 or no bit in poly is set.
+func NewCRC(poly *BitArray) *CRC {
+	c := &CRC{poly: Clone(poly)}
+	c.Reset()
+	return c
+}
+
+// Reset resets the CRC to the empty message.
+func (c *CRC) Reset() {
+	c.rem = GF2Mod(New(1), c.poly)
+}
+
+// Update appends the bits of ba to the message. As with [Concat] the bits of ba
+// follow the bits of the message, i.e. they are the new lowest coefficients.
+func (c *CRC) Update(ba *BitArray) {
+	c.rem = GF2Mod(Concat(c.rem, ba), c.poly)
+}
+
+// Sum returns the CRC of the message, i.e. the remainder of the message multiplied by
+// x^n divided by the generator polynomial with n = poly.Size() - 1.
+func (c *CRC) Sum() *BitArray {
+	return GF2Mod(Concat(c.rem, New(c.poly.size-1)), c.poly)
+}
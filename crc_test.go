@@ -0,0 +1,50 @@
+package bitarray
+
+import "testing"
+
+func TestCRC(t *testing.T) {
+	tests := []struct {
+		poly, msg string
+		want      string
+	}{
+		{"1011", "11010011101100", "100"},
+		{"1011", "0", "000"},
+		{"100000111", "0110000101100010", "11001001"},
+		{"10011", "1101011011", "1110"},
+	}
+	for i, test := range tests {
+		c := NewCRC(MustParse(test.poly))
+		c.Update(MustParse(test.msg))
+		if got := c.Sum().String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+		c.Reset()
+		c.Update(MustParse(test.msg))
+		if got := c.Sum().String(); got != test.want {
+			t.Errorf("%d: got %q after reset, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestCRCUpdate(t *testing.T) {
+	poly := MustParse("100000111")
+	tests := []struct {
+		s1, s2 string
+	}{
+		{"1101001", "1101100"},
+		{"0", "1"},
+		{"0101010101010101", "111"},
+		{"1", "10000000000000000001"},
+	}
+	for i, test := range tests {
+		ba1, ba2 := MustParse(test.s1), MustParse(test.s2)
+		c1 := NewCRC(poly)
+		c1.Update(ba1)
+		c1.Update(ba2)
+		c2 := NewCRC(poly)
+		c2.Update(Concat(ba1, ba2))
+		if got, want := c1.Sum(), c2.Sum(); !got.Equal(want) {
+			t.Errorf("%d: got %q, want %q", i, got, want)
+		}
+	}
+}
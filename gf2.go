@@ -0,0 +1,150 @@
+package bitarray
+
+import "math/bits"
+
+// DotGF2 returns the dot product of a and b over GF(2), i.e. it reports whether
+// an odd number of bits are set in both arrays. Panics if the sizes are not equal.
+func DotGF2(a, b *BitArray) bool {
+	a.checkSize(b)
+	cnt := 0
+	for i := range a.data {
+		cnt += bits.OnesCount8(a.data[i] & b.data[i])
+	}
+	return cnt%2 == 1
+}
+
+// Syndrome returns the syndrome H * received over GF(2). The bit at index i of the
+// result is the dot product of row H[i] and received. Panics if len(H) == 0 or the
+// size of one of the rows does not equal the size of received.
+func Syndrome(H []*BitArray, received *BitArray) *BitArray {
+	result := New(len(H))
+	for i, row := range H {
+		if DotGF2(row, received) {
+			result.set(i)
+		}
+	}
+	return result
+}
+
+// RowReduceGF2 transforms the rows in place into reduced row echelon form over GF(2)
+// and returns the rank. Pivots are chosen from the most significant bit downwards
+// and the rows with pivots are moved to the front, followed by the zero rows.
+// Panics if the sizes of the rows are not equal.
+func RowReduceGF2(rows []*BitArray) int {
+	if len(rows) == 0 {
+		return 0
+	}
+	for _, row := range rows[1:] {
+		rows[0].checkSize(row)
+	}
+	rank := 0
+	for col := rows[0].size - 1; col >= 0 && rank < len(rows); col-- {
+		p := rank
+		for p < len(rows) && !rows[p].get(col) {
+			p++
+		}
+		if p == len(rows) {
+			continue
+		}
+		rows[rank], rows[p] = rows[p], rows[rank]
+		for i, row := range rows {
+			if i != rank && row.get(col) {
+				row.Xor(rows[rank])
+			}
+		}
+		rank++
+	}
+	return rank
+}
+
+// NullSpaceGF2 returns a basis of the null space over GF(2) of the matrix formed by
+// the rows, i.e. vectors v with [Syndrome](rows, v) being 0. The rows are not modified.
+// Returns nil if there are no rows or the null space is {0}. Panics if the sizes of
+// the rows are not equal.
+func NullSpaceGF2(rows []*BitArray) []*BitArray {
+	if len(rows) == 0 {
+		return nil
+	}
+	reduced := make([]*BitArray, len(rows))
+	for i, row := range rows {
+		reduced[i] = Clone(row)
+	}
+	rank := RowReduceGF2(reduced)
+	size := reduced[0].size
+	pivots := make([]int, rank)
+	isPivot := make([]bool, size)
+	for i, row := range reduced[:rank] {
+		p := size - 1
+		for !row.get(p) {
+			p--
+		}
+		pivots[i] = p
+		isPivot[p] = true
+	}
+	var basis []*BitArray
+	for col := 0; col < size; col++ {
+		if isPivot[col] {
+			continue
+		}
+		v := New(size, col)
+		for i, row := range reduced[:rank] {
+			if row.get(col) {
+				v.set(pivots[i])
+			}
+		}
+		basis = append(basis, v)
+	}
+	return basis
+}
+
+// CarrylessMultiply returns the product of a and b as polynomials over GF(2) where the
+// bit at index i is the coefficient of x^i. The size of the result is
+// a.Size() + b.Size() - 1.
+func CarrylessMultiply(a, b *BitArray) *BitArray {
+	result := New(a.size + b.size - 1)
+	for i := 0; i < a.size; i++ {
+		if !a.get(i) {
+			continue
+		}
+		for j := 0; j < b.size; j++ {
+			if b.get(j) {
+				result.flip(i + j)
+			}
+		}
+	}
+	return result
+}
+
+// GF2Mod returns the remainder of the division of a by modulus as polynomials over
+// GF(2) where the bit at index i is the coefficient of x^i. The size of the result
+// is modulus.Size() - 1. Panics if modulus.Size() < 2 or no bit in modulus is set.
+func GF2Mod(a, modulus *BitArray) *BitArray {
+	if modulus.size < 2 {
+		panic("modulus size must be >= 2")
+	}
+	d := modulus.size - 1
+	for d >= 0 && !modulus.get(d) {
+		d--
+	}
+	if d < 0 {
+		panic("modulus must not be 0")
+	}
+	r := Clone(a)
+	for i := a.size - 1; i >= d; i-- {
+		if !r.get(i) {
+			continue
+		}
+		for j := 0; j <= d; j++ {
+			if modulus.get(j) {
+				r.flip(i - d + j)
+			}
+		}
+	}
+	result := New(modulus.size - 1)
+	for i := 0; i < min(r.size, result.size); i++ {
+		if r.get(i) {
+			result.set(i)
+		}
+	}
+	return result
+}
@@ -0,0 +1,213 @@
+package bitarray
+
+import "testing"
+
+// hamming74 returns the parity-check matrix of the Hamming(7,4) code.
+func hamming74() []*BitArray {
+	return []*BitArray{
+		MustParse("1010101"),
+		MustParse("1100110"),
+		MustParse("1111000"),
+	}
+}
+
+func TestDotGF2(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   bool
+	}{
+		{"0000", "1111", false},
+		{"0001", "1111", true},
+		{"0101", "1111", false},
+		{"0111", "0101", false},
+		{"1111111111", "1000000001", false},
+		{"1111111111", "1100000001", true},
+	}
+	for i, test := range tests {
+		if got := DotGF2(MustParse(test.s1), MustParse(test.s2)); got != test.want {
+			t.Errorf("%d: got %t, want %t", i, got, test.want)
+		}
+	}
+}
+
+func TestDotGF2DiffSize(t *testing.T) {
+	defer func() { recover() }()
+	DotGF2(New(4), New(5))
+	t.Error("did not panic")
+}
+
+func TestSyndrome(t *testing.T) {
+	H := hamming74()
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"0000000", "000"},
+		{"1111111", "000"},
+		{"0000111", "000"},
+		{"0010111", "101"},
+		{"0000110", "001"},
+		{"1000111", "111"},
+	}
+	for i, test := range tests {
+		if got := Syndrome(H, MustParse(test.s)).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestSyndromeDiffSize(t *testing.T) {
+	defer func() { recover() }()
+	Syndrome(hamming74(), New(8))
+	t.Error("did not panic")
+}
+
+func TestRowReduceGF2(t *testing.T) {
+	tests := []struct {
+		rows []string
+		want []string
+		rank int
+	}{
+		{[]string{}, []string{}, 0},
+		{[]string{"0000"}, []string{"0000"}, 0},
+		{[]string{"1100", "0110", "1010"}, []string{"1010", "0110", "0000"}, 2},
+		{[]string{"0001", "0010", "0100", "1000"}, []string{"1000", "0100", "0010", "0001"}, 4},
+		{[]string{"0000", "0011", "0111"}, []string{"0100", "0011", "0000"}, 2},
+		{[]string{"1111111111", "1000000001", "0111111110"}, []string{"1000000001", "0111111110", "0000000000"}, 2},
+	}
+	for i, test := range tests {
+		rows := make([]*BitArray, len(test.rows))
+		for j, s := range test.rows {
+			rows[j] = MustParse(s)
+		}
+		rank := RowReduceGF2(rows)
+		if rank != test.rank {
+			t.Errorf("%d: got rank %d, want %d", i, rank, test.rank)
+		}
+		for j, row := range rows {
+			if got := row.String(); got != test.want[j] {
+				t.Errorf("%d/%d: got %q, want %q", i, j, got, test.want[j])
+			}
+		}
+	}
+}
+
+func TestRowReduceGF2DiffSize(t *testing.T) {
+	defer func() { recover() }()
+	RowReduceGF2([]*BitArray{New(4), New(5)})
+	t.Error("did not panic")
+}
+
+func TestNullSpaceGF2(t *testing.T) {
+	tests := []struct {
+		rows []string
+		want []string
+	}{
+		{[]string{}, nil},
+		{[]string{"110", "011"}, []string{"111"}},
+		{[]string{"1100", "0110", "1010"}, []string{"0001", "1110"}},
+		{[]string{"01", "10"}, nil},
+		{[]string{"1010101", "1100110", "1111000"}, []string{"1100001", "1010010", "0110100", "1111000"}},
+	}
+	for i, test := range tests {
+		rows := make([]*BitArray, len(test.rows))
+		for j, s := range test.rows {
+			rows[j] = MustParse(s)
+		}
+		basis := NullSpaceGF2(rows)
+		if len(basis) != len(test.want) {
+			t.Fatalf("%d: got %d vectors, want %d", i, len(basis), len(test.want))
+		}
+		for j, v := range basis {
+			if got := v.String(); got != test.want[j] {
+				t.Errorf("%d/%d: got %q, want %q", i, j, got, test.want[j])
+			}
+			if s := Syndrome(rows, v); s.Count() != 0 {
+				t.Errorf("%d/%d: got syndrome %q", i, j, s)
+			}
+		}
+		for j, row := range rows {
+			if got := row.String(); got != test.rows[j] {
+				t.Errorf("%d/%d: row modified: got %q, want %q", i, j, got, test.rows[j])
+			}
+		}
+	}
+}
+
+func clmul(a, b uint64) uint64 {
+	var r uint64
+	for i := 0; i < 64; i++ {
+		if b&(1<<i) != 0 {
+			r ^= a << i
+		}
+	}
+	return r
+}
+
+func TestCarrylessMultiply(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   string
+	}{
+		{"11", "11", "101"},
+		{"111", "11", "1001"},
+		{"1011", "1", "1011"},
+		{"0", "0", "0"},
+	}
+	for i, test := range tests {
+		if got := CarrylessMultiply(MustParse(test.s1), MustParse(test.s2)).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+	tests2 := []struct {
+		size1, size2 int
+		v1, v2       uint64
+	}{
+		{8, 8, 0xa5, 0x3c},
+		{10, 7, 0x2f1, 0x55},
+		{16, 16, 0xffff, 0xffff},
+		{20, 13, 0xabcde, 0x1234},
+	}
+	for i, test := range tests2 {
+		ba := CarrylessMultiply(FromUint64(test.size1, test.v1), FromUint64(test.size2, test.v2))
+		if ba.Size() != test.size1+test.size2-1 {
+			t.Errorf("%d: got size %d, want %d", i, ba.Size(), test.size1+test.size2-1)
+		}
+		got, _ := ba.Uint64()
+		if want := clmul(test.v1, test.v2); got != want {
+			t.Errorf("%d: got %#x, want %#x", i, got, want)
+		}
+	}
+}
+
+func TestGF2Mod(t *testing.T) {
+	tests := []struct {
+		s, mod string
+		want   string
+	}{
+		{"11010011101100000", "1011", "100"},
+		{"11010011101100100", "1011", "000"},
+		{"101", "11", "0"},
+		{"111", "11", "1"},
+		{"1", "1011", "001"},
+		{"0000", "0011", "000"},
+		{"1101", "0011", "001"},
+		{"1000000000", "10011", "1010"},
+	}
+	for i, test := range tests {
+		if got := GF2Mod(MustParse(test.s), MustParse(test.mod)).String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestGF2ModPanic(t *testing.T) {
+	tests := []string{"1", "000"}
+	for i, test := range tests {
+		func() {
+			defer func() { recover() }()
+			GF2Mod(New(4), MustParse(test))
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
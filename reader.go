@@ -0,0 +1,52 @@
+package bitarray
+
+import "io"
+
+// Reader reads the bits of a BitArray sequentially, starting at index 0.
+type Reader struct {
+	ba  *BitArray
+	pos int
+}
+
+// NewReader returns a new Reader reading from ba.
+func NewReader(ba *BitArray) *Reader {
+	return &Reader{ba: ba}
+}
+
+// Remaining returns the number of bits that have not been read yet.
+func (r *Reader) Remaining() int {
+	return r.ba.size - r.pos
+}
+
+// ReadBit reads the next bit. It returns io.EOF if there are no bits left.
+func (r *Reader) ReadBit() (bool, error) {
+	if r.pos >= r.ba.size {
+		return false, io.EOF
+	}
+	b := r.ba.get(r.pos)
+	r.pos++
+	return b, nil
+}
+
+// ReadBits reads the next n bits and returns them as a uint64 where the first bit read
+// is bit 0. It returns io.EOF if there are no bits left and io.ErrUnexpectedEOF if
+// fewer than n bits are left; in both cases nothing is consumed. Panics if n < 0 or n > 64.
+func (r *Reader) ReadBits(n int) (uint64, error) {
+	if n < 0 || n > 64 {
+		panic("n out of range")
+	}
+	if rem := r.Remaining(); rem < n {
+		if rem == 0 {
+			return 0, io.EOF
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	var v uint64
+	for i := range n {
+		if r.ba.get(r.pos + i) {
+			v |= 1 << i
+		}
+	}
+	r.pos += n
+	return v, nil
+}
@@ -0,0 +1,83 @@
+package bitarray
+
+import (
+	"io"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	// fields from index 0: 3 bits = 5, 1 bit = 1, 8 bits = 0xA7, 2 bits = 2
+	r := NewReader(MustParse("10" + "10100111" + "1" + "101"))
+	if got := r.Remaining(); got != 14 {
+		t.Errorf("got %d, want 14", got)
+	}
+	tests := []struct {
+		n    int
+		want uint64
+	}{
+		{3, 5},
+		{1, 1},
+		{8, 0xA7},
+		{0, 0},
+		{2, 2},
+	}
+	for i, test := range tests {
+		got, err := r.ReadBits(test.n)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if got != test.want {
+			t.Errorf("%d: got %#x, want %#x", i, got, test.want)
+		}
+	}
+	if got := r.Remaining(); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+	if _, err := r.ReadBit(); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+	if _, err := r.ReadBits(1); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+}
+
+func TestReaderReadBit(t *testing.T) {
+	r := NewReader(MustParse("1100000101"))
+	var got []bool
+	for {
+		b, err := r.ReadBit()
+		if err == io.EOF {
+			break
+		}
+		got = append(got, b)
+	}
+	want := []bool{true, false, true, false, false, false, false, false, true, true}
+	if len(got) != len(want) {
+		t.Fatalf("got %d bits, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: got %t, want %t", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReaderShort(t *testing.T) {
+	r := NewReader(MustParse("1100000101"))
+	if _, err := r.ReadBits(11); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+	if got := r.Remaining(); got != 10 {
+		t.Errorf("got %d, want 10", got)
+	}
+}
+
+func TestReaderPanic(t *testing.T) {
+	for i, n := range []int{-1, 65} {
+		func() {
+			defer func() { recover() }()
+			NewReader(New(100)).ReadBits(n)
+			t.Errorf("%d: did not panic", i)
+		}()
+	}
+}
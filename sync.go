@@ -0,0 +1,120 @@
+package bitarray
+
+import "sync"
+
+// SyncBitArray is a BitArray that is safe for concurrent use. Methods that only read
+// the bit array take a read lock, methods that modify it take a write lock.
+type SyncBitArray struct {
+	mu sync.RWMutex
+	ba *BitArray
+}
+
+// NewSync creates a new SyncBitArray from ba. The caller must not use ba afterwards.
+func NewSync(ba *BitArray) *SyncBitArray {
+	return &SyncBitArray{ba: ba}
+}
+
+// BitArray returns a copy of the underlying BitArray.
+func (s *SyncBitArray) BitArray() *BitArray {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Clone(s.ba)
+}
+
+// Set sets the bit at index idx to 1.
+func (s *SyncBitArray) Set(idx int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.Set(idx)
+}
+
+// Unset sets the bit at index idx to 0.
+func (s *SyncBitArray) Unset(idx int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.Unset(idx)
+}
+
+// Toggle toggles the state of the bit at index idx and reports whether it is set after being toggled.
+func (s *SyncBitArray) Toggle(idx int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ba.Toggle(idx)
+}
+
+// Get reports whether the bit at index idx is set.
+func (s *SyncBitArray) Get(idx int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ba.Get(idx)
+}
+
+// Clear sets all bits to 0.
+func (s *SyncBitArray) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.Clear()
+}
+
+// SetAll sets all bits to 1.
+func (s *SyncBitArray) SetAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.SetAll()
+}
+
+// And sets s = s & other (bitwise AND).
+func (s *SyncBitArray) And(other *BitArray) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.And(other)
+}
+
+// Or sets s = s | other (bitwise OR).
+func (s *SyncBitArray) Or(other *BitArray) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.Or(other)
+}
+
+// Xor sets s = s ^ other (bitwise XOR).
+func (s *SyncBitArray) Xor(other *BitArray) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.Xor(other)
+}
+
+// AndNot sets s = s &^ other (bit clear).
+func (s *SyncBitArray) AndNot(other *BitArray) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.AndNot(other)
+}
+
+// Not sets s = ^s.
+func (s *SyncBitArray) Not() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ba.Not()
+}
+
+// Count returns the number of set bits.
+func (s *SyncBitArray) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ba.Count()
+}
+
+// Size returns the size of the bit array.
+func (s *SyncBitArray) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ba.Size()
+}
+
+// String returns a string representation of the bit array.
+func (s *SyncBitArray) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ba.String()
+}
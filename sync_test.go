@@ -0,0 +1,63 @@
+package bitarray
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSyncBitArray(t *testing.T) {
+	s := NewSync(New(10))
+	s.Set(0)
+	s.Set(9)
+	if !s.Toggle(1) || !s.Get(1) || s.Get(2) {
+		t.Error("got wrong bits")
+	}
+	s.Unset(1)
+	if got, want := s.String(), "1000000001"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	s.Or(MustParse("0000011000"))
+	s.And(MustParse("1000010001"))
+	s.Xor(MustParse("0000000011"))
+	s.AndNot(MustParse("1000000000"))
+	if got, want := s.String(), "0000010010"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	s.Not()
+	if got, want := s.BitArray().String(), "1111101101"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	s.SetAll()
+	if got := s.Count(); got != s.Size() {
+		t.Errorf("got %d, want %d", got, s.Size())
+	}
+	s.Clear()
+	if got := s.Count(); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+}
+
+func TestSyncBitArrayConcurrent(t *testing.T) {
+	const n = 64
+	s := NewSync(New(n))
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(idx int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Set(idx)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Count()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.Count(); got != n {
+		t.Errorf("got %d, want %d", got, n)
+	}
+}